@@ -0,0 +1,690 @@
+/*
+ * Copyright (C) 2017 eschao <esc.chao@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/eschao/config/utils"
+	"gopkg.in/yaml.v2"
+)
+
+// Problem describes a problem found in a configuration file
+type Problem struct {
+	File     string
+	Position        // position of the key, zero if unknown
+	Key      string // key in file, e.g: log.level
+	Message  string
+}
+
+func (this Problem) String() string {
+	s := this.File
+	if this.Line > 0 {
+		s += fmt.Sprintf(":%d:%d", this.Line, this.Column)
+	}
+	if this.Key != "" {
+		s += ": " + this.Key
+	}
+	return s + ": " + this.Message
+}
+
+// CheckError reports all problems found in configuration files
+type CheckError struct {
+	Problems []Problem
+}
+
+func (this *CheckError) Error() string {
+	problems := make([]string, len(this.Problems))
+	for i, p := range this.Problems {
+		problems[i] = p.String()
+	}
+	return "Invalid configuration files: " + strings.Join(problems, "; ")
+}
+
+// checker checks configuration files against a structure type
+type checker struct {
+	problems []Problem
+	unknown  []Problem       // problems of unknown keys
+	provided map[string]bool // Go paths of provided fields
+
+	// the file being checked
+	file       string
+	configType string
+	tag        string
+	fold       bool
+	positions  map[string]Position
+}
+
+// Check checks a configuration file against given structure type, a
+// reflect.Type or a value of the structure or its pointer, without parsing
+// the file into it. It reports every:
+//   - unknown key, except the include key or @include lines
+//   - value which can't be converted to the field type or overflows it
+//   - value which fails the validation tags, see Validate
+//   - required field which is provided by neither the file nor a default
+//     value
+//
+// The included files are checked as well. The problems are returned together
+// as *CheckError with the line and column of keys, other errors, e.g: the
+// file can't be read or decoded, are returned directly
+func Check(i interface{}, configFile string) error {
+	typeOfStruct, err := structTypeOf(i)
+	if err != nil {
+		return err
+	}
+
+	c := &checker{provided: make(map[string]bool)}
+	if err := c.checkFile(typeOfStruct, configFile, nil); err != nil {
+		return err
+	}
+
+	c.file, c.positions = configFile, nil
+	c.checkRequired(typeOfStruct, "", "")
+	if len(c.problems) > 0 {
+		return &CheckError{Problems: c.problems}
+	}
+	return nil
+}
+
+// checkFile checks the included files and then the given configuration file
+func (this *checker) checkFile(t reflect.Type, configFile string,
+	stack []string) error {
+	configType, err := getConfigFileType(configFile)
+	if err != nil {
+		return err
+	}
+
+	raw, err := ioutil.ReadFile(configFile)
+	if err != nil {
+		return fmt.Errorf("Can't open %s config file. %s", configType,
+			err.Error())
+	}
+
+	includes, err := getIncludes(raw, configType)
+	if err != nil {
+		return fmt.Errorf("%s: %s", configFile, err.Error())
+	}
+
+	includes, stack, err = resolveIncludes(configFile, includes, stack)
+	if err != nil {
+		return err
+	}
+
+	for _, include := range includes {
+		if err := this.checkFile(t, include, stack); err != nil {
+			return err
+		}
+	}
+
+	return this.checkData(t, configFile, raw, configType)
+}
+
+// checkData checks the data of a configuration file
+func (this *checker) checkData(t reflect.Type, configFile string, raw []byte,
+	configType string) error {
+	this.file, this.configType = configFile, configType
+	this.positions = keyPositions(raw, configType)
+	this.tag, this.fold = configType, true
+	start := len(this.problems)
+
+	switch configType {
+	case JSONConfigType:
+		var tree map[string]interface{}
+		decoder := json.NewDecoder(bytes.NewReader(raw))
+		decoder.UseNumber()
+		if err := decoder.Decode(&tree); err != nil {
+			return fmt.Errorf("%s: %s", configFile, err.Error())
+		}
+		this.checkTree(t, tree, "", "", true)
+	case YamlConfigType:
+		var tree map[interface{}]interface{}
+		if err := yaml.Unmarshal(raw, &tree); err != nil {
+			return fmt.Errorf("%s: %s", configFile, err.Error())
+		}
+		this.fold = false
+		this.checkTree(t, stringKeys(tree), "", "", true)
+	case TomlConfigType:
+		var tree map[string]interface{}
+		if _, err := toml.Decode(string(raw), &tree); err != nil {
+			return fmt.Errorf("%s: %s", configFile, err.Error())
+		}
+		this.checkTree(t, tree, "", "", true)
+	case PropConfigType:
+		props, err := readProperties(bytes.NewReader(raw))
+		if err != nil {
+			return fmt.Errorf("%s: %s", configFile, err.Error())
+		}
+
+		values := make(map[string][]string, len(props))
+		for key, value := range props {
+			values[key] = []string{value}
+		}
+		this.tag = "prop"
+		this.checkKeys(t, values)
+	case IniConfigType:
+		values, err := readIni(bytes.NewReader(raw))
+		if err != nil {
+			return fmt.Errorf("%s: %s", configFile, err.Error())
+		}
+		this.checkKeys(t, values)
+	}
+
+	sortProblems(this.problems[start:])
+	return nil
+}
+
+// checkUnknownKeys returns *CheckError if the configuration data has keys
+// which match no field of structure type
+func checkUnknownKeys(t reflect.Type, configFile string, raw []byte,
+	configType string) error {
+	c := &checker{provided: make(map[string]bool)}
+	if err := c.checkData(t, configFile, raw, configType); err != nil {
+		return err
+	}
+
+	if len(c.unknown) > 0 {
+		sortProblems(c.unknown)
+		return &CheckError{Problems: c.unknown}
+	}
+	return nil
+}
+
+// sortProblems sorts the problems of a file by their positions
+func sortProblems(problems []Problem) {
+	sort.SliceStable(problems, func(i, j int) bool {
+		if problems[i].Line != problems[j].Line {
+			return problems[i].Line < problems[j].Line
+		}
+		if problems[i].Column != problems[j].Column {
+			return problems[i].Column < problems[j].Column
+		}
+		return problems[i].Key < problems[j].Key
+	})
+}
+
+// report adds a problem of a key in the file being checked, the position of
+// an array element is the position of the array
+func (this *checker) report(key string, format string, args ...interface{}) {
+	problem := Problem{File: this.file, Key: key,
+		Message: fmt.Sprintf(format, args...)}
+	for k := key; k != "" && this.positions != nil; {
+		if position, ok := lookupPosition(this.positions, k); ok {
+			problem.Position = position
+			break
+		}
+
+		index := strings.LastIndexAny(k, ".[")
+		if index < 0 {
+			break
+		}
+		k = k[:index]
+	}
+	this.problems = append(this.problems, problem)
+}
+
+// reportUnknown adds a problem of unknown key
+func (this *checker) reportUnknown(key string) {
+	this.report(key, "unknown key")
+	this.unknown = append(this.unknown, this.problems[len(this.problems)-1])
+}
+
+// checkTree checks a decoded JSON, Yaml or TOML tree against structure type,
+// the keys which match no field are unknown
+func (this *checker) checkTree(t reflect.Type, tree map[string]interface{},
+	prefix, path string, root bool) {
+	used := make(map[string]bool)
+	this.checkTreeFields(t, tree, prefix, path, used)
+
+	for key := range tree {
+		if !used[key] && !(root && key == IncludeKey) {
+			this.reportUnknown(joinPath(prefix, key, "."))
+		}
+	}
+}
+
+// checkTreeFields checks the fields of structure type which are provided by
+// the tree, and records the matched keys
+func (this *checker) checkTreeFields(t reflect.Type,
+	tree map[string]interface{}, prefix, path string, used map[string]bool) {
+	for i := 0; i < t.NumField(); i++ {
+		structOfField := t.Field(i)
+		if structOfField.PkgPath != "" {
+			continue
+		}
+
+		name, ok := treeName(structOfField, this.tag)
+		if !ok {
+			continue
+		}
+
+		fieldPath := joinPath(path, structOfField.Name, ".")
+		if name == "" {
+			if typeOfField := derefType(structOfField.Type); typeOfField.Kind() ==
+				reflect.Struct {
+				this.checkTreeFields(typeOfField, tree, prefix, fieldPath, used)
+			}
+			continue
+		}
+
+		key, ok := this.treeKey(tree, name)
+		if !ok {
+			continue
+		}
+
+		used[key] = true
+		this.provided[fieldPath] = true
+
+		v := reflect.New(structOfField.Type).Elem()
+		value, key := tree[key], joinPath(prefix, key, ".")
+		if this.convert(v, value, key, fieldPath) && value != nil {
+			this.validate(structOfField, v, key)
+		}
+	}
+}
+
+// treeKey returns the key in tree which matches the given name
+func (this *checker) treeKey(tree map[string]interface{},
+	name string) (string, bool) {
+	if _, ok := tree[name]; ok {
+		return name, true
+	}
+
+	if this.fold {
+		for key := range tree {
+			if strings.EqualFold(key, name) {
+				return key, true
+			}
+		}
+	}
+	return "", false
+}
+
+// convert converts a decoded value to the type of v and sets v with it, the
+// problems are reported if it can't be converted
+func (this *checker) convert(v reflect.Value, raw interface{}, key,
+	path string) bool {
+	if raw == nil {
+		return true
+	}
+
+	if v.Kind() == reflect.Ptr {
+		v.Set(reflect.New(v.Type().Elem()))
+		v = v.Elem()
+	}
+
+	// TOML decodes arrays of tables as []map[string]interface{}
+	if tables, ok := raw.([]map[string]interface{}); ok {
+		values := make([]interface{}, len(tables))
+		for i, table := range tables {
+			values[i] = table
+		}
+		raw = values
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		tree, ok := raw.(map[string]interface{})
+		if !ok {
+			return this.mismatch(v, raw, key)
+		}
+		this.checkTree(v.Type(), tree, key, path, false)
+		return false
+	case reflect.Map:
+		tree, ok := raw.(map[string]interface{})
+		if !ok {
+			return this.mismatch(v, raw, key)
+		}
+
+		v.Set(reflect.MakeMapWithSize(v.Type(), len(tree)))
+		valid := true
+		for k, e := range tree {
+			mapKey := reflect.New(v.Type().Key()).Elem()
+			if err := utils.SetValue(mapKey, k, ""); err != nil {
+				this.report(key+"."+k, "invalid key of %s", v.Type().String())
+				valid = false
+				continue
+			}
+
+			mapValue := reflect.New(v.Type().Elem()).Elem()
+			if this.convert(mapValue, e, key+"."+k, path) {
+				v.SetMapIndex(mapKey, mapValue)
+			} else {
+				valid = false
+			}
+		}
+		return valid
+	case reflect.Slice:
+		values, ok := raw.([]interface{})
+		if !ok {
+			return this.mismatch(v, raw, key)
+		}
+
+		v.Set(reflect.MakeSlice(v.Type(), len(values), len(values)))
+		valid := true
+		for i, e := range values {
+			if !this.convert(v.Index(i), e, fmt.Sprintf("%s[%d]", key, i),
+				path) {
+				valid = false
+			}
+		}
+		return valid
+	case reflect.Interface:
+		v.Set(reflect.ValueOf(raw))
+		return true
+	}
+
+	return this.convertScalar(v, raw, key)
+}
+
+// convertScalar converts a decoded scalar value to the type of v
+func (this *checker) convertScalar(v reflect.Value, raw interface{},
+	key string) bool {
+	var number string
+	switch raw.(type) {
+	case json.Number, int, int64, uint64, float64:
+		number = fmt.Sprint(raw)
+	case map[string]interface{}, []interface{}:
+		return this.mismatch(v, raw, key)
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		b, ok := raw.(bool)
+		if !ok {
+			return this.mismatch(v, raw, key)
+		}
+		v.SetBool(b)
+	case reflect.String:
+		s, ok := raw.(string)
+		if !ok && this.configType != YamlConfigType {
+			return this.mismatch(v, raw, key)
+		} else if !ok {
+			// Yaml scalars could be decoded as strings
+			s = fmt.Sprint(raw)
+		}
+		v.SetString(s)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Int64:
+		n, err := strconv.ParseInt(number, 10, 64)
+		if err != nil && !errors.Is(err, strconv.ErrRange) {
+			return this.mismatch(v, raw, key)
+		}
+		if err != nil || v.OverflowInt(n) {
+			return this.overflow(v, raw, key)
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64:
+		if strings.HasPrefix(number, "-") {
+			return this.overflow(v, raw, key)
+		}
+		n, err := strconv.ParseUint(number, 10, 64)
+		if err != nil && !errors.Is(err, strconv.ErrRange) {
+			return this.mismatch(v, raw, key)
+		}
+		if err != nil || v.OverflowUint(n) {
+			return this.overflow(v, raw, key)
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		if number == "" {
+			return this.mismatch(v, raw, key)
+		}
+		f, err := strconv.ParseFloat(number, 64)
+		if err != nil || v.OverflowFloat(f) {
+			return this.overflow(v, raw, key)
+		}
+		v.SetFloat(f)
+	default:
+		this.report(key, "unsupported type %s", v.Type().String())
+		return false
+	}
+	return true
+}
+
+// mismatch reports a value which can't be converted to the type of v
+func (this *checker) mismatch(v reflect.Value, raw interface{},
+	key string) bool {
+	this.report(key, "expect %s instead of %s", typeString(v.Type()),
+		valueString(raw))
+	return false
+}
+
+// overflow reports a value which overflows the type of v
+func (this *checker) overflow(v reflect.Value, raw interface{},
+	key string) bool {
+	this.report(key, "%s is out of range of %s", valueString(raw),
+		v.Type().String())
+	return false
+}
+
+// typeString describes a type in configuration files
+func typeString(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Struct, reflect.Map:
+		return "object"
+	case reflect.Slice:
+		return "array"
+	}
+	return t.String()
+}
+
+// valueString describes a decoded value in configuration files
+func valueString(raw interface{}) string {
+	switch v := raw.(type) {
+	case string:
+		return strconv.Quote(v)
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	}
+	return fmt.Sprint(raw)
+}
+
+// validate validates a converted field value with validation tags, the zero
+// value provided in files is validated as well
+func (this *checker) validate(f reflect.StructField, v reflect.Value,
+	key string) {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+
+	for _, rule := range validateRules {
+		param, ok := f.Tag.Lookup(rule)
+		if !ok {
+			continue
+		}
+
+		valid, err := validateValue(v, rule, param)
+		if err != nil {
+			this.report(key, "invalid %s tag: %s", rule, err.Error())
+		} else if !valid {
+			fe := FieldError{Rule: rule, Param: param, Value: v.Interface()}
+			this.report(key, "%v %s", fe.Value, fe.message())
+		}
+	}
+}
+
+// checkKeys checks the flat keys of Properties or INI against structure
+// type, the keys which match no field are unknown
+func (this *checker) checkKeys(t reflect.Type, values map[string][]string) {
+	used := make(map[string]bool)
+	this.checkKeyFields(t, values, "", "", used)
+
+	for key := range values {
+		if !used[key] && key != IncludeDirective {
+			this.reportUnknown(key)
+		}
+	}
+}
+
+// checkKeyFields checks the fields of structure type which are provided by
+// the flat keys, and records the matched keys
+func (this *checker) checkKeyFields(t reflect.Type, values map[string][]string,
+	prefix, path string, used map[string]bool) {
+	for i := 0; i < t.NumField(); i++ {
+		structOfField := t.Field(i)
+		if structOfField.PkgPath != "" {
+			continue
+		}
+
+		name := structOfField.Tag.Get(this.tag)
+		fieldPath := joinPath(path, structOfField.Name, ".")
+		if typeOfField := derefType(structOfField.Type); typeOfField.Kind() ==
+			reflect.Struct {
+			subPrefix := prefix
+			if name != "" {
+				subPrefix = prefix + name + "."
+			}
+			this.checkKeyFields(typeOfField, values, subPrefix, fieldPath, used)
+			continue
+		}
+
+		key := prefix + name
+		keyValues, ok := values[key]
+		if name == "" || !ok {
+			continue
+		}
+
+		used[key] = true
+		this.provided[fieldPath] = true
+
+		v := reflect.New(structOfField.Type).Elem()
+		if v.Kind() == reflect.Ptr {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+
+		if this.setKeyValue(reflect.Indirect(v), structOfField, keyValues,
+			key) {
+			this.validate(structOfField, v, key)
+		}
+	}
+}
+
+// setKeyValue sets v with the values of a flat key like the Properties and
+// INI parsers do, the repeated INI keys are the elements of a slice
+func (this *checker) setKeyValue(v reflect.Value, f reflect.StructField,
+	values []string, key string) bool {
+	var err error
+	if v.Kind() == reflect.Slice && len(values) > 1 {
+		v.Set(reflect.MakeSlice(v.Type(), len(values), len(values)))
+		for i, value := range values {
+			if err = utils.SetValue(v.Index(i), value, ""); err != nil {
+				break
+			}
+		}
+	} else {
+		sp, ok := f.Tag.Lookup("separator")
+		if !ok {
+			sp = ":"
+		}
+		err = utils.SetValue(v, values[len(values)-1], sp)
+	}
+
+	if err == nil {
+		return true
+	}
+
+	var numError *strconv.NumError
+	value := strings.Join(values, ", ")
+	if errors.Is(err, strconv.ErrRange) {
+		return this.overflow(v, value, key)
+	} else if errors.As(err, &numError) {
+		return this.mismatch(v, value, key)
+	}
+	this.report(key, "%s", err.Error())
+	return false
+}
+
+// checkRequired reports the required fields which are not provided and have
+// no default value, the nested structures of nil pointers are optional
+func (this *checker) checkRequired(t reflect.Type, prefix, path string) {
+	flat := this.tag == "prop" || this.tag == "ini"
+	for i := 0; i < t.NumField(); i++ {
+		structOfField := t.Field(i)
+		if structOfField.PkgPath != "" {
+			continue
+		}
+
+		name, ok := structOfField.Tag.Get(this.tag), true
+		if !flat {
+			name, ok = treeName(structOfField, this.tag)
+		}
+		if !ok {
+			continue
+		}
+
+		fieldPath := joinPath(path, structOfField.Name, ".")
+		if typeOfField := derefType(structOfField.Type); typeOfField.Kind() ==
+			reflect.Struct {
+			if structOfField.Type.Kind() == reflect.Ptr &&
+				!this.providedUnder(fieldPath) {
+				continue
+			}
+
+			subPrefix := joinPath(prefix, name, ".")
+			if flat && name != "" {
+				subPrefix = prefix + name + "."
+			} else if flat {
+				subPrefix = prefix
+			}
+			this.checkRequired(typeOfField, subPrefix, fieldPath)
+			continue
+		}
+
+		required, _ := strconv.ParseBool(structOfField.Tag.Get("required"))
+		_, hasDefault := structOfField.Tag.Lookup("default")
+		if !required || hasDefault || this.provided[fieldPath] {
+			continue
+		}
+
+		key := joinPath(prefix, name, ".")
+		if flat {
+			key = prefix + name
+		}
+		if name == "" {
+			key = fieldPath
+		}
+		this.report(key, "missing required field")
+	}
+}
+
+// providedUnder checks if a field or any of its nested fields is provided
+func (this *checker) providedUnder(path string) bool {
+	if this.provided[path] {
+		return true
+	}
+
+	for p := range this.provided {
+		if strings.HasPrefix(p, path+".") {
+			return true
+		}
+	}
+	return false
+}
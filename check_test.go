@@ -0,0 +1,128 @@
+/*
+ * Copyright (C) 2017 eschao <esc.chao@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+
+	"github.com/eschao/config/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func checkProblems(t *testing.T, err error) []string {
+	var checkErr *CheckError
+	if !assert.True(t, errors.As(err, &checkErr)) {
+		return nil
+	}
+
+	problems := make([]string, len(checkErr.Problems))
+	for i, p := range checkErr.Problems {
+		problems[i] = p.String()
+	}
+	return problems
+}
+
+func TestCheckYaml(t *testing.T) {
+	_, curTestFile, _, _ := runtime.Caller(0)
+	file := filepath.Dir(curTestFile) + "/test/check/validate.yaml"
+
+	assert := assert.New(t)
+	conf := test.ValidateConfig{}
+	assert.Equal([]string{
+		file + ":1:1: name: Bad_Name must match pattern ^[a-z][a-z0-9-]*$",
+		file + ":2:1: port: 70000 must be at most 65535",
+		file + ":3:1: ratio: expect float32 instead of \"high\"",
+		file + ":4:1: retries: 300 is out of range of uint8",
+		file + ":5:1: hosts: [a b c d] length must be at most 3",
+		file + ":6:1: codes: [200 999] must be at most 599",
+		file + ":8:3: log.level: verbose must be one of {debug|warning|error}",
+		file + ":9:3: log.pth: unknown key",
+		file + ":10:1: extra: unknown key",
+	}, checkProblems(t, Check(&conf, file)))
+
+	// the structure is not parsed
+	assert.Equal(test.ValidateConfig{}, conf)
+
+	// zero values provided in files are validated
+	file = filepath.Join(t.TempDir(), "zero.yaml")
+	assert.NoError(os.WriteFile(file, []byte("port: 0\nratio: 0\n"+
+		"retries:\n"), 0644))
+	assert.Equal([]string{
+		file + ":1:1: port: 0 must be at least 1",
+	}, checkProblems(t, Check(&conf, file)))
+}
+
+func TestCheckMissingRequired(t *testing.T) {
+	_, curTestFile, _, _ := runtime.Caller(0)
+	file := filepath.Dir(curTestFile) + "/test/check/required.json"
+
+	assert := assert.New(t)
+	assert.Equal([]string{
+		file + ":2:3: port: expect int instead of \"8080\"",
+		file + ":4:3: user: expect string instead of 1",
+		file + ": host: missing required field",
+		file + ": db.password: missing required field",
+	}, checkProblems(t, Check(reflect.TypeOf(test.RequiredConfig{}), file)))
+}
+
+func TestCheckIncludes(t *testing.T) {
+	_, curTestFile, _, _ := runtime.Caller(0)
+	path := filepath.Dir(curTestFile) + "/test/include"
+
+	assert := assert.New(t)
+	assert.NoError(Check(test.MergeConfig{}, path+"/service.json"))
+	assert.NoError(Check(test.DBConfig{}, path+"/db.properties"))
+	assert.NoError(Check(test.DBConfig{}, path+"/db.ini"))
+	assert.Error(Check(test.MergeConfig{}, path+"/cycle-a.yaml"))
+}
+
+func TestCheckFlatKeys(t *testing.T) {
+	dir := t.TempDir()
+	prop := filepath.Join(dir, "db.properties")
+	ini := filepath.Join(dir, "db.ini")
+	toml := filepath.Join(dir, "db.toml")
+
+	assert := assert.New(t)
+	assert.NoError(os.WriteFile(prop, []byte("dbHost = localhost\n"+
+		"dbPort = abc\ndbHots = typo\nlog.level = error\n"), 0644))
+	assert.NoError(os.WriteFile(ini, []byte("port = 99999999999\n"+
+		"[log]\nlevel = verbose\n"), 0644))
+	assert.NoError(os.WriteFile(toml, []byte("dbPort = \"5432\"\n"+
+		"[log]\npath = 1\n"), 0644))
+
+	assert.Equal([]string{
+		prop + ":2:1: dbPort: expect int instead of \"abc\"",
+		prop + ":3:1: dbHots: unknown key",
+	}, checkProblems(t, Check(test.DBConfig{}, prop)))
+
+	assert.Equal([]string{
+		ini + ":1:1: port: \"99999999999\" is out of range of int",
+		ini + ":3:1: log.level: verbose must be one of {debug|warning|error}",
+	}, checkProblems(t, Check(test.DBConfig{}, ini)))
+
+	assert.Equal([]string{
+		toml + ":1:1: dbPort: expect int instead of \"5432\"",
+		toml + ":3:1: log.path: expect string instead of 1",
+	}, checkProblems(t, Check(test.DBConfig{}, toml)))
+
+	assert.Error(Check(test.DBConfig{}, filepath.Join(dir, "not-exist.json")))
+	assert.Error(Check("config", prop))
+}
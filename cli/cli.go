@@ -149,6 +149,22 @@ func New(name string) *Command {
 	return &cmd
 }
 
+// NewWithErrorHandling creates a command with given name and error handling,
+// unlike NewWith, it doesn't change the error handling of other commands
+func NewWithErrorHandling(name string,
+	errHandling flag.ErrorHandling) *Command {
+	cmd := Command{
+		Name:        name,
+		FlagSet:     flag.NewFlagSet(name, errHandling),
+		SubCommands: make(map[string]*Command),
+	}
+
+	if usageHandler != nil {
+		cmd.FlagSet.Usage = usageHandler(&cmd)
+	}
+	return &cmd
+}
+
 // NewWith creates a command with given name, error handling and customized
 // usage function
 func NewWith(name string, errHandling flag.ErrorHandling,
@@ -232,8 +248,9 @@ func (this *Command) addFlag(v reflect.Value, f reflect.StructField) error {
 	kind := v.Kind()
 	switch kind {
 	case reflect.Bool:
+		// use current value as default to keep the value set by other parsers
 		this.FlagSet.BoolVar((*bool)(unsafe.Pointer(v.UnsafeAddr())), name,
-			false, usage)
+			v.Bool(), usage)
 		return nil
 	case reflect.String,
 		reflect.Int8,
@@ -277,7 +294,7 @@ func (this *Command) createSubCommand(tag reflect.StructTag) *Command {
 	}
 
 	cmd.Name = name
-	cmd.FlagSet = flag.NewFlagSet(name, errorHandling)
+	cmd.FlagSet = flag.NewFlagSet(name, this.FlagSet.ErrorHandling())
 	cmd.Usage = usage
 
 	if usageHandler != nil {
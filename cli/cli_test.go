@@ -250,3 +250,26 @@ func TestCommandWithSlices(t *testing.T) {
 	assert.Equal(200, conf.Values[1])
 	assert.Equal(300, conf.Values[2])
 }
+
+func TestCommandKeepsBoolValue(t *testing.T) {
+	assert := assert.New(t)
+	typesConfig := test.TypesConfig{BoolValue: true}
+	cmd := New("Types")
+	assert.NoError(cmd.Init(&typesConfig))
+	assert.NoError(cmd.Parse([]string{"-str", "xxx"}))
+	assert.Equal(true, typesConfig.BoolValue)
+}
+
+func TestCommandWithErrorHandling(t *testing.T) {
+	assert := assert.New(t)
+	handling := errorHandling
+	serviceConfig := test.ServiceConfig{}
+	cmd := NewWithErrorHandling("Service", flag.ContinueOnError)
+	assert.NoError(cmd.Init(&serviceConfig))
+	assert.Equal(flag.ContinueOnError,
+		cmd.SubCommands["database"].FlagSet.ErrorHandling())
+	assert.Error(cmd.Parse([]string{"database", "-dbPrt", "1"}))
+
+	// the error handling of other commands is not changed
+	assert.Equal(handling, errorHandling)
+}
@@ -16,14 +16,19 @@
 package config
 
 import (
+	"bytes"
 	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
+	"io/fs"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"reflect"
+	"strings"
 
+	"github.com/BurntSushi/toml"
 	"github.com/eschao/config/cli"
 	"github.com/eschao/config/env"
 	"github.com/eschao/config/utils"
@@ -35,12 +40,21 @@ const (
 	DefaultJSONConfig = "config.json"
 	DefaultYamlConfig = "config.yaml"
 	DefaultPropConfig = "config.properties"
+	DefaultTomlConfig = "config.toml"
+	DefaultIniConfig  = "config.ini"
 )
 
+// ProfileEnv is the environment variable to set the active profile. The
+// profile configuration file config.<profile>.<ext> is loaded on top of the
+// base configuration file config.<ext>
+const ProfileEnv = "CONFIG_PROFILE"
+
 const (
 	JSONConfigType = "json"
 	YamlConfigType = "yaml"
 	PropConfigType = "properties"
+	TomlConfigType = "toml"
+	IniConfigType  = "ini"
 )
 
 // ParseDefault parses the given structure, extract default value from its tag
@@ -149,9 +163,9 @@ func ParseCli(i interface{}) error {
 // ParseConfig parses given structure interface and set it with default
 // configuration file.
 // configFlag is a command line flag to tell where to locate configure file.
-// If the config file doesn't exist, the default config fill will be searched
-// under the same folder with the fixed order: config.json, config.yaml and
-// config.properties
+// If the config file doesn't exist, the default config file will be searched
+// in the default search paths with the fixed order: config.json, config.yaml,
+// config.yml, config.properties, config.toml and config.ini
 func ParseConfig(i interface{}, configFlag string) error {
 	configFile := flag.String(configFlag, "", "Specifiy configuration file")
 	flag.Parse()
@@ -159,9 +173,40 @@ func ParseConfig(i interface{}, configFlag string) error {
 }
 
 // ParseConfigFile parses given structure interface and set its value with
-// the specified configuration file
+// the specified configuration file. If the active profile is set by
+// CONFIG_PROFILE environment variable, the profile configuration file is
+// merged on top of it, see ParseProfileConfigFile
 func ParseConfigFile(i interface{}, configFile string) error {
-	var err error
+	return ParseProfileConfigFile(i, configFile, os.Getenv(ProfileEnv))
+}
+
+// ParseProfileConfigFile parses given structure interface with the specified
+// configuration file and its profile configuration file, which is in the same
+// folder and named by inserting the profile before the extension name, e.g:
+// config.prod.yaml for config.yaml with prod profile. The profile file is
+// deep-merged on top of the base one like ParseConfigFiles, and it is skipped
+// if it doesn't exist. If the profile is empty, only the base file is parsed
+func ParseProfileConfigFile(i interface{}, configFile string,
+	profile string) error {
+	return parseProfileConfigFile(i, configFile, profile, false)
+}
+
+// ParseConfigFileStrict parses like ParseConfigFile, but the keys which
+// match no field in the configuration files are errors instead of being
+// ignored, e.g: a typo. The include key and @include lines are not unknown.
+// The unknown keys are returned together as *CheckError with their positions
+func ParseConfigFileStrict(i interface{}, configFile string) error {
+	return parseProfileConfigFile(i, configFile, os.Getenv(ProfileEnv), true)
+}
+
+// parseProfileConfigFile parses the configuration file and its profile file
+func parseProfileConfigFile(i interface{}, configFile string, profile string,
+	strict bool) error {
+	valueOfStruct, err := structValueOf(i)
+	if err != nil {
+		return err
+	}
+
 	if configFile == "" {
 		configFile, err = getDefaultConfigFile()
 		if err != nil {
@@ -169,84 +214,229 @@ func ParseConfigFile(i interface{}, configFile string) error {
 		}
 	}
 
-	configType, err := getConfigFileType(configFile)
+	configFiles := []string{configFile}
+	if profileFile, ok := getProfileConfigFile(configFile, profile); ok {
+		configFiles = append(configFiles, profileFile)
+	}
+
+	return (&merger{strict: strict}).mergeFiles(valueOfStruct, configFiles)
+}
+
+// ParseFS parses given structure interface and set its value with the named
+// configuration file in the file system, e.g: embed.FS. The config type is
+// analyzed from the file extension name, or detected from the file content
+// if the extension name is unknown
+func ParseFS(i interface{}, fsys fs.FS, name string) error {
+	raw, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return fmt.Errorf("Can't open config file. %s", err.Error())
+	}
+
+	configType, err := getConfigFileType(name)
 	if err != nil {
-		return err
+		configType = ""
+	}
+	return ParseBytes(i, raw, configType)
+}
+
+// ParseReader parses given structure interface and set its value with the
+// configurations read from the reader. The configType could be json, yaml,
+// properties, toml or ini. If it is empty, the type is detected from content
+func ParseReader(i interface{}, r io.Reader, configType string) error {
+	raw, err := ioutil.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("Can't read config. %s", err.Error())
+	}
+
+	return ParseBytes(i, raw, configType)
+}
+
+// ParseBytes parses given structure interface and set its value with the
+// configuration data. The configType could be json, yaml, properties, toml or
+// ini. If it is empty, the type is detected from data
+func ParseBytes(i interface{}, data []byte, configType string) error {
+	if configType == "" {
+		configType = detectConfigType(data)
 	}
 
 	switch configType {
 	case JSONConfigType:
-		return parseJSON(i, configFile)
+		return parseJSON(i, data)
 	case YamlConfigType:
-		return parseYaml(i, configFile)
+		return parseYaml(i, data)
 	case PropConfigType:
-		return parseProp(i, configFile)
+		return parseProp(i, data)
+	case TomlConfigType:
+		return parseToml(i, data)
+	case IniConfigType:
+		return parseIni(i, data)
 	default:
-		return fmt.Errorf("Can't support config file: %s", configFile)
+		return fmt.Errorf("Can't support config type: %s", configType)
 	}
+}
 
-	return nil
+// parseJSON parses JSON data and set structure with its value
+func parseJSON(i interface{}, data []byte) error {
+	return json.Unmarshal(data, i)
 }
 
-// parseJSON parses JSON file and set structure with its value
-func parseJSON(i interface{}, jsonFile string) error {
-	raw, err := ioutil.ReadFile(jsonFile)
+// parseYaml parses Yaml data and set structure with its value
+func parseYaml(i interface{}, data []byte) error {
+	return yaml.Unmarshal(data, i)
+}
+
+// parseProp parses Properties data and set structure with its value
+func parseProp(i interface{}, data []byte) error {
+	valueOfStruct, err := structValueOf(i)
+	if err != nil {
+		return err
+	}
+
+	props, err := readProperties(bytes.NewReader(data))
 	if err != nil {
-		return fmt.Errorf("Can't open json config file. %s", err.Error())
+		return fmt.Errorf("Can't parse properties config. %s", err.Error())
 	}
 
-	return json.Unmarshal(raw, i)
+	return setPropValue(valueOfStruct, props, "")
 }
 
-// parseYaml parses Yaml file and set structure with its value
-func parseYaml(i interface{}, yamlFile string) error {
-	raw, err := ioutil.ReadFile(yamlFile)
+// parseToml parses TOML data and set structure with its value
+func parseToml(i interface{}, data []byte) error {
+	_, err := toml.Decode(string(data), i)
+	return err
+}
+
+// parseIni parses INI data and set structure with its value
+func parseIni(i interface{}, data []byte) error {
+	valueOfStruct, err := structValueOf(i)
+	if err != nil {
+		return err
+	}
+
+	values, err := readIni(bytes.NewReader(data))
 	if err != nil {
-		return fmt.Errorf("Can't open yaml config file. %s", err.Error())
+		return fmt.Errorf("Can't parse ini config. %s", err.Error())
 	}
 
-	return yaml.Unmarshal(raw, i)
+	return setIniValue(valueOfStruct, values, "")
 }
 
-// parseProp parses Properties file and set structure with its value
-func parseProp(i interface{}, propFile string) error {
-	return fmt.Errorf("Properties config has not implemented!")
+// structValueOf returns the structure value which the given structure
+// pointer points to
+func structValueOf(i interface{}) (reflect.Value, error) {
+	ptrRef := reflect.ValueOf(i)
+	if ptrRef.Kind() != reflect.Ptr || ptrRef.IsNil() {
+		return ptrRef, fmt.Errorf(
+			"Expect a structure pointer type instead of %s",
+			ptrRef.Kind().String())
+	}
+
+	valueOfStruct := ptrRef.Elem()
+	if valueOfStruct.Kind() != reflect.Struct {
+		return valueOfStruct, fmt.Errorf(
+			"Expect a structure pointer type instead of %s",
+			valueOfStruct.Kind().String())
+	}
+
+	return valueOfStruct, nil
 }
 
-// getDefaultConfigFile returns a existing default config file. The checking
-// order is fixed with beginning from: config.json to config.yaml and
-// config.properties
-func getDefaultConfigFile() (string, error) {
-	exe, err := os.Executable()
-	if err != nil {
-		return "", fmt.Errorf("Can't find default config file. %s", err.Error())
+// detectConfigType detects config type from the content of data:
+//   - json: begins with '{'
+//   - toml: valid TOML document with TOML-specific syntax, see hasTomlSyntax
+//   - ini: has [section] line
+//   - properties: the first key/value line is separated by '='
+//   - yaml: others
+func detectConfigType(data []byte) string {
+	content := strings.TrimSpace(string(data))
+	if strings.HasPrefix(content, "{") {
+		return JSONConfigType
+	}
+
+	var tomlValues map[string]interface{}
+	if hasTomlSyntax(content) {
+		if _, err := toml.Decode(content, &tomlValues); err == nil &&
+			len(tomlValues) > 0 {
+			return TomlConfigType
+		}
 	}
 
-	path := filepath.Dir(exe) + string(filepath.Separator)
+	configType := ""
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' || line[0] == ';' || line[0] == '!' {
+			continue
+		}
+
+		if line[0] == '[' && line[len(line)-1] == ']' {
+			return IniConfigType
+		}
+
+		if configType == "" {
+			equal := strings.Index(line, "=")
+			colon := strings.Index(line, ":")
+			if equal > 0 && (colon < 0 || equal < colon) {
+				configType = PropConfigType
+			} else {
+				configType = YamlConfigType
+			}
+		}
+	}
 
-	// check json config
-	jsonConfig := path + DefaultJSONConfig
-	if _, err := os.Stat(jsonConfig); err == nil {
-		return jsonConfig, nil
+	if configType == "" {
+		return YamlConfigType
 	}
+	return configType
+}
+
+// hasTomlSyntax checks if the content has syntax which is TOML-specific: an
+// array of tables [[...]], or a value which is a quoted string, an array or
+// an inline table. Plain key=value lines are left to Properties and INI
+func hasTomlSyntax(content string) bool {
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "[[") {
+			return true
+		}
+
+		if line == "" || line[0] == '#' || line[0] == '[' {
+			continue
+		}
 
-	// check yaml config
-	yamlConfig := path + DefaultYamlConfig
-	if _, err := os.Stat(yamlConfig); err == nil {
-		return yamlConfig, nil
+		if index := strings.Index(line, "="); index > 0 {
+			value := strings.TrimSpace(line[index+1:])
+			if value != "" && strings.ContainsRune("\"'[{", rune(value[0])) {
+				return true
+			}
+		}
 	}
+	return false
+}
 
-	// check prop config
-	propConfig := path + DefaultPropConfig
-	if _, err := os.Stat(propConfig); err == nil {
-		return propConfig, nil
+// getDefaultConfigFile returns a existing default config file which is
+// searched in the default search paths of the executable, see
+// DefaultSearchPaths and FindConfigFile
+func getDefaultConfigFile() (string, error) {
+	return FindConfigFile(DefaultConfigNames, DefaultSearchPaths(appName()))
+}
+
+// getProfileConfigFile returns the profile configuration file of the given
+// one if the profile is not empty and the file exists
+func getProfileConfigFile(configFile string, profile string) (string, bool) {
+	if profile == "" {
+		return "", false
 	}
 
-	return "", fmt.Errorf("No default config file found in path: %s", path)
+	ext := filepath.Ext(configFile)
+	profileFile := strings.TrimSuffix(configFile, ext) + "." + profile + ext
+	if _, err := os.Stat(profileFile); err != nil {
+		return "", false
+	}
+	return profileFile, true
 }
 
 // getConfigFileType analyzes config file extension name and return
-// corresponding type: json, yaml or properties
+// corresponding type: json, yaml, properties, toml or ini
 func getConfigFileType(configFile string) (string, error) {
 	ext := filepath.Ext(configFile)
 	if ext == ".json" {
@@ -255,6 +445,10 @@ func getConfigFileType(configFile string) (string, error) {
 		return YamlConfigType, nil
 	} else if ext == ".properties" || ext == ".prop" {
 		return PropConfigType, nil
+	} else if ext == ".toml" {
+		return TomlConfigType, nil
+	} else if ext == ".ini" {
+		return IniConfigType, nil
 	}
 
 	return "", fmt.Errorf("Can't support file type: %s", configFile)
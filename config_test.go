@@ -20,7 +20,9 @@ import (
 	"path/filepath"
 	"runtime"
 	"strconv"
+	"strings"
 	"testing"
+	"testing/fstest"
 
 	"github.com/eschao/config/test"
 	"github.com/stretchr/testify/assert"
@@ -114,3 +116,232 @@ func TestYamlConfigFile(t *testing.T) {
 	assert.Equal(DB_LOG_PATH, conf.Log.Path)
 	assert.Equal(DB_LOG_LEVEL, conf.Log.Level)
 }
+
+func TestPropConfigFile(t *testing.T) {
+	_, curTestFile, _, _ := runtime.Caller(0)
+	path := filepath.Dir(curTestFile)
+
+	conf := test.DBConfig{}
+	assert := assert.New(t)
+	assert.NoError(ParseConfigFile(&conf, path+"/test/config.properties"))
+	assert.Equal(DB_HOST, conf.Host)
+	assert.Equal(DB_PORT, conf.Port)
+	assert.Equal(DB_USER, conf.User)
+	assert.Equal(DB_PASSWORD, conf.Password)
+	assert.Equal(DB_LOG_PATH, conf.Log.Path)
+	assert.Equal(DB_LOG_LEVEL, conf.Log.Level)
+}
+
+func TestTomlConfigFile(t *testing.T) {
+	_, curTestFile, _, _ := runtime.Caller(0)
+	path := filepath.Dir(curTestFile)
+
+	conf := test.DBConfig{}
+	assert := assert.New(t)
+	assert.NoError(ParseConfigFile(&conf, path+"/test/config.toml"))
+	assert.Equal(DB_HOST, conf.Host)
+	assert.Equal(DB_PORT, conf.Port)
+	assert.Equal(DB_USER, conf.User)
+	assert.Equal(DB_PASSWORD, conf.Password)
+	assert.Equal(DB_LOG_PATH, conf.Log.Path)
+	assert.Equal(DB_LOG_LEVEL, conf.Log.Level)
+}
+
+func TestTomlConfigWithSlices(t *testing.T) {
+	type slicesConfig struct {
+		Paths  []string         `toml:"paths"`
+		Values []int            `toml:"values"`
+		Logs   []test.LogConfig `toml:"logs"`
+	}
+
+	file := filepath.Join(t.TempDir(), "slices.toml")
+	data := `
+paths = ["/var", "/usr"]
+values = [1, 2, 3]
+
+[[logs]]
+path = "/var/log/a"
+
+[[logs]]
+path = "/var/log/b"
+level = "debug"
+`
+	assert := assert.New(t)
+	assert.NoError(os.WriteFile(file, []byte(data), 0644))
+
+	conf := slicesConfig{}
+	assert.NoError(ParseConfigFile(&conf, file))
+	assert.Equal([]string{"/var", "/usr"}, conf.Paths)
+	assert.Equal([]int{1, 2, 3}, conf.Values)
+	assert.Equal(2, len(conf.Logs))
+	assert.Equal("/var/log/b", conf.Logs[1].Path)
+	assert.Equal("debug", conf.Logs[1].Level)
+}
+
+func TestIniConfigFile(t *testing.T) {
+	_, curTestFile, _, _ := runtime.Caller(0)
+	path := filepath.Dir(curTestFile)
+
+	conf := test.ServiceConfig{Login: &test.LoginConfig{}}
+	assert := assert.New(t)
+	assert.NoError(ParseConfigFile(&conf, path+"/test/service.ini"))
+	assert.Equal(SERVICE_HOST, conf.Host)
+	assert.Equal(SERVICE_PORT, conf.Port)
+	assert.Equal(SERVICE_LOG_PATH, conf.Log.Path)
+	assert.Equal(SERVICE_LOG_LEVEL, conf.Log.Level)
+	assert.Equal(LOGIN_USER, conf.Login.User)
+	assert.Equal(LOGIN_PASSWORD, conf.Login.Password)
+	assert.Equal(DB_HOST, conf.DBConfig.Host)
+	assert.Equal(DB_PORT, conf.DBConfig.Port)
+	assert.Equal(DB_USER, conf.DBConfig.User)
+	assert.Equal(DB_PASSWORD, conf.DBConfig.Password)
+	assert.Equal(DB_LOG_PATH, conf.DBConfig.Log.Path)
+	assert.Equal(DB_LOG_LEVEL, conf.DBConfig.Log.Level)
+}
+
+func TestProfileConfigFile(t *testing.T) {
+	_, curTestFile, _, _ := runtime.Caller(0)
+	path := filepath.Dir(curTestFile)
+
+	conf := test.AppConfig{}
+	assert := assert.New(t)
+	assert.NoError(ParseProfileConfigFile(&conf, path+"/test/app.yaml",
+		"prod"))
+	assert.Equal("yaml-app", conf.Name)
+	assert.Equal(443, conf.Port)
+	assert.Equal("/var/log/app", conf.Log.Path)
+	assert.Equal("error", conf.Log.Level)
+
+	os.Setenv(ProfileEnv, "prod")
+	defer os.Unsetenv(ProfileEnv)
+	conf = test.AppConfig{}
+	assert.NoError(ParseConfigFile(&conf, path+"/test/app.yaml"))
+	assert.Equal(443, conf.Port)
+
+	// missing profile file is skipped
+	conf = test.AppConfig{}
+	assert.NoError(ParseProfileConfigFile(&conf, path+"/test/app.yaml",
+		"staging"))
+	assert.Equal(9090, conf.Port)
+}
+
+func TestParseConfigFileStrict(t *testing.T) {
+	_, curTestFile, _, _ := runtime.Caller(0)
+	path := filepath.Dir(curTestFile)
+
+	assert := assert.New(t)
+	conf := test.DBConfig{}
+	assert.NoError(ParseConfigFileStrict(&conf, path+"/test/config.yaml"))
+	assert.Equal(DB_HOST, conf.Host)
+
+	// include key and @include lines are not unknown
+	mergeConf := test.MergeConfig{}
+	assert.NoError(ParseConfigFileStrict(&mergeConf,
+		path+"/test/include/service.json"))
+	conf = test.DBConfig{}
+	assert.NoError(ParseConfigFileStrict(&conf,
+		path+"/test/include/db.properties"))
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"db.json":       "{\"dbHots\": \"localhost\", \"log\": {\"levle\": \"debug\"}}",
+		"db.yaml":       "dbHost: localhost\ndbHots: localhost\n",
+		"db.properties": "dbHost = localhost\ndbHots = localhost\n",
+		"db.ini":        "host = localhost\nhots = localhost\n",
+		"db.toml":       "dbHost = \"localhost\"\ndbHots = \"localhost\"\n",
+	}
+	for name, data := range files {
+		assert.NoError(os.WriteFile(filepath.Join(dir, name), []byte(data),
+			0644))
+
+		conf = test.DBConfig{}
+		file := filepath.Join(dir, name)
+		err := ParseConfigFileStrict(&conf, file)
+		assert.Error(err, name)
+		assert.Contains(err.Error(), "unknown key", name)
+		assert.NoError(ParseConfigFile(&conf, file), name)
+	}
+
+	conf = test.DBConfig{}
+	err := ParseConfigFileStrict(&conf, filepath.Join(dir, "db.json"))
+	assert.Equal("Invalid configuration files: "+dir+"/db.json:1:2: dbHots: "+
+		"unknown key; "+dir+"/db.json:1:33: log.levle: unknown key",
+		err.Error())
+	// nothing is parsed
+	assert.Equal(test.DBConfig{}, conf)
+}
+
+func TestParseFS(t *testing.T) {
+	_, curTestFile, _, _ := runtime.Caller(0)
+	path := filepath.Dir(curTestFile)
+
+	assert := assert.New(t)
+	for _, name := range []string{"config.json", "config.yaml",
+		"config.properties", "config.toml"} {
+		conf := test.DBConfig{}
+		assert.NoError(ParseFS(&conf, os.DirFS(path+"/test"), name), name)
+		assert.Equal(DB_HOST, conf.Host, name)
+		assert.Equal(DB_PORT, conf.Port, name)
+		assert.Equal(DB_LOG_LEVEL, conf.Log.Level, name)
+	}
+
+	// detect config type from content
+	fsys := fstest.MapFS{
+		"config": &fstest.MapFile{Data: []byte("dbHost: test-db-host")},
+	}
+	conf := test.DBConfig{}
+	assert.NoError(ParseFS(&conf, fsys, "config"))
+	assert.Equal(DB_HOST, conf.Host)
+
+	assert.Error(ParseFS(&conf, fsys, "not-exist.json"))
+}
+
+func TestParseReader(t *testing.T) {
+	assert := assert.New(t)
+	conf := test.DBConfig{}
+	assert.NoError(ParseReader(&conf,
+		strings.NewReader(`{"dbHost": "test-db-host"}`), JSONConfigType))
+	assert.Equal(DB_HOST, conf.Host)
+
+	conf = test.DBConfig{}
+	assert.NoError(ParseReader(&conf,
+		strings.NewReader("dbPort = 9090"), ""))
+	assert.Equal(DB_PORT, conf.Port)
+
+	assert.Error(ParseReader(&conf, strings.NewReader(""), "xml"))
+}
+
+func TestParseBytes(t *testing.T) {
+	assert := assert.New(t)
+	conf := test.ServiceConfig{}
+	assert.NoError(ParseBytes(&conf, []byte("[database]\nhost = test-db-host"),
+		IniConfigType))
+	assert.Equal(DB_HOST, conf.DBConfig.Host)
+
+	dbConf := test.DBConfig{}
+	assert.NoError(ParseBytes(&dbConf, []byte("log.path = /var/log/db"),
+		PropConfigType))
+	assert.Equal(DB_LOG_PATH, dbConf.Log.Path)
+	assert.Error(ParseBytes(dbConf, []byte("log.path = /var/log/db"),
+		PropConfigType))
+}
+
+func TestDetectConfigType(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal(JSONConfigType, detectConfigType([]byte(` {"host": "x"}`)))
+	assert.Equal(YamlConfigType, detectConfigType([]byte("# yaml\nhost: x")))
+	assert.Equal(YamlConfigType, detectConfigType([]byte("log:\n  path: /x")))
+	assert.Equal(TomlConfigType, detectConfigType([]byte("host = \"x\"")))
+	assert.Equal(TomlConfigType, detectConfigType([]byte("[log]\npath = \"/x\"")))
+	assert.Equal(IniConfigType, detectConfigType([]byte("; ini\n[log]\npath = /x")))
+	assert.Equal(PropConfigType, detectConfigType([]byte("! prop\nhost = x")))
+	assert.Equal(PropConfigType, detectConfigType([]byte("url = http://x")))
+	assert.Equal(PropConfigType, detectConfigType([]byte("port=8080")))
+	assert.Equal(PropConfigType,
+		detectConfigType([]byte("port = 8080\ndebug = true")))
+	assert.Equal(IniConfigType, detectConfigType([]byte("[db]\nport=5432")))
+	assert.Equal(TomlConfigType,
+		detectConfigType([]byte("port = 8080\nhosts = [1, 2]")))
+	assert.Equal(TomlConfigType,
+		detectConfigType([]byte("[[servers]]\nport = 8080")))
+}
@@ -0,0 +1,155 @@
+/*
+ * Copyright (C) 2017 eschao <esc.chao@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/eschao/config/utils"
+)
+
+const (
+	// DefaultDirTag is the default tag to define file names of fields in a
+	// configuration directory
+	DefaultDirTag = "dir"
+
+	// dirDataLink is the symbolic link of Kubernetes mounted volumes which
+	// points to the current version of files
+	dirDataLink = "..data"
+)
+
+// ParseDir parses given structure interface with a directory where every
+// file name is a configuration name defined by dir tag and the file content
+// is its value, see ParseDirWith
+func ParseDir(i interface{}, dir string) error {
+	return ParseDirWith(i, dir, DefaultDirTag)
+}
+
+// ParseDirWith parses given structure interface with a directory, the file
+// names are defined by the given tag. The tag of a nested structure is the
+// name of its sub-directory, but with env tag, the file names are the
+// environment variable names in the same directory, e.g: /run/secrets/DB_HOST.
+// The trailing newlines of file contents are trimmed and the missing files
+// are skipped.
+// If the directory is a mounted Kubernetes ConfigMap or Secret, its files are
+// read from the version that ..data links to, so they are consistent
+func ParseDirWith(i interface{}, dir string, tag string) error {
+	return parseDir(i, dir, "", tag, nil)
+}
+
+// parseDir parses given structure interface with a directory, the prefix is
+// the environment variable name prefix for env tag. The origins of fields
+// read from files are recorded if origins is not nil
+func parseDir(i interface{}, dir string, prefix string, tag string,
+	origins map[string]Origin) error {
+	valueOfStruct, err := structValueOf(i)
+	if err != nil {
+		return err
+	}
+
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return fmt.Errorf("Can't open config directory: %s", dir)
+	}
+
+	if data, err := filepath.EvalSymlinks(filepath.Join(dir,
+		dirDataLink)); err == nil {
+		dir = data
+	}
+
+	reader := &dirReader{tag: tag, origins: origins}
+	return reader.setDirValue(valueOfStruct, dir, prefix, "")
+}
+
+// dirReader reads field values from files in a configuration directory
+type dirReader struct {
+	tag     string
+	origins map[string]Origin // nil if origins are not recorded
+}
+
+// setDirValue sets structure with the file contents in the directory, the
+// prefix is used to concatenate environment variable names for env tag and
+// the path is the Go path of structure
+func (this *dirReader) setDirValue(v reflect.Value, dir string, prefix string,
+	path string) error {
+	tag := this.tag
+	typeOfStruct := v.Type()
+	for i := 0; i < v.NumField(); i++ {
+		valueOfField := v.Field(i)
+		structOfField := typeOfStruct.Field(i)
+		if structOfField.PkgPath != "" {
+			continue
+		}
+
+		name := structOfField.Tag.Get(tag)
+		if valueOfField.Kind() == reflect.Ptr {
+			if valueOfField.IsNil() ||
+				valueOfField.Elem().Kind() != reflect.Struct {
+				continue
+			}
+			valueOfField = valueOfField.Elem()
+		}
+
+		if valueOfField.Kind() == reflect.Struct {
+			subDir, subPrefix := dir, prefix
+			if tag == "env" {
+				subPrefix = prefix + name
+			} else if name != "" {
+				subDir = filepath.Join(dir, name)
+			}
+
+			if err := this.setDirValue(valueOfField, subDir, subPrefix,
+				joinPath(path, structOfField.Name, ".")); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if name == "" {
+			continue
+		}
+
+		file := filepath.Join(dir, prefix+name)
+		raw, err := ioutil.ReadFile(file)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return fmt.Errorf("Can't read config file %s. %s", file,
+				err.Error())
+		}
+
+		sp, ok := structOfField.Tag.Lookup("separator")
+		if !ok {
+			sp = ":"
+		}
+
+		if err := utils.SetValue(valueOfField,
+			strings.TrimRight(string(raw), "\r\n"), sp); err != nil {
+			return fmt.Errorf("%s: %s", file, err.Error())
+		}
+
+		if this.origins != nil {
+			setOrigin(this.origins, joinPath(path, structOfField.Name, "."),
+				Origin{Source: DirSource, File: file})
+		}
+	}
+
+	return nil
+}
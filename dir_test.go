@@ -0,0 +1,112 @@
+/*
+ * Copyright (C) 2017 eschao <esc.chao@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/eschao/config/test"
+	"github.com/stretchr/testify/assert"
+)
+
+// writeFiles writes files with contents into the directory
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		file := filepath.Join(dir, name)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(file), 0755))
+		assert.NoError(t, os.WriteFile(file, []byte(content), 0644))
+	}
+}
+
+func TestParseDir(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"name":      "dir-app\n",
+		"port":      "8080\n",
+		"hosts":     "a.example.com,b.example.com",
+		"log/path":  "/var/log/app",
+		"log/level": "error\n",
+	})
+
+	assert := assert.New(t)
+	conf := test.DirConfig{}
+	assert.NoError(ParseDir(&conf, dir))
+	assert.Equal("dir-app", conf.Name)
+	assert.Equal(8080, conf.Port)
+	assert.Equal([]string{"a.example.com", "b.example.com"}, conf.Hosts)
+	assert.Equal("/var/log/app", conf.Log.Path)
+	assert.Equal("error", conf.Log.Level)
+
+	writeFiles(t, dir, map[string]string{"port": "xxx"})
+	assert.Error(ParseDir(&conf, dir))
+	assert.Error(ParseDir(&conf, filepath.Join(dir, "not-exist")))
+}
+
+func TestParseDirWithEnvNames(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"NAME":      "env-app",
+		"LOG_LEVEL": "debug",
+	})
+
+	assert := assert.New(t)
+	conf := test.DirConfig{Port: 9090}
+	assert.NoError(ParseDirWith(&conf, dir, "env"))
+	assert.Equal("env-app", conf.Name)
+	assert.Equal(9090, conf.Port)
+	assert.Equal("debug", conf.Log.Level)
+
+	writeFiles(t, dir, map[string]string{"APP_NAME": "prefix-app"})
+	conf = test.DirConfig{}
+	loader := New(WithSources(DirSource), WithDir(dir), WithDirTag("env"),
+		WithEnvPrefix("APP_"))
+	assert.NoError(loader.Load(&conf))
+	assert.Equal("prefix-app", conf.Name)
+
+	loader = New(WithSources(DirSource))
+	assert.Error(loader.Load(&conf))
+}
+
+func TestParseDirWithDataLink(t *testing.T) {
+	// layout of a Kubernetes mounted ConfigMap
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"..2024_01_01/name":      "v1",
+		"..2024_01_01/log/level": "warning",
+		"..2024_01_02/name":      "v2",
+		"..2024_01_02/log/level": "error",
+	})
+
+	assert := assert.New(t)
+	assert.NoError(os.Symlink("..2024_01_01", filepath.Join(dir, "..data")))
+	assert.NoError(os.Symlink("..data/name", filepath.Join(dir, "name")))
+	assert.NoError(os.Symlink("..data/log", filepath.Join(dir, "log")))
+
+	conf := test.DirConfig{}
+	assert.NoError(ParseDir(&conf, dir))
+	assert.Equal("v1", conf.Name)
+	assert.Equal("warning", conf.Log.Level)
+
+	// update atomically by swapping the ..data link
+	assert.NoError(os.Symlink("..2024_01_02", filepath.Join(dir, "..data_tmp")))
+	assert.NoError(os.Rename(filepath.Join(dir, "..data_tmp"),
+		filepath.Join(dir, "..data")))
+	assert.NoError(ParseDir(&conf, dir))
+	assert.Equal("v2", conf.Name)
+	assert.Equal("error", conf.Log.Level)
+}
@@ -0,0 +1,473 @@
+/*
+ * Copyright (C) 2017 eschao <esc.chao@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// EnvFormat is the format of environment variable lines, e.g: NAME=value
+	EnvFormat = "env"
+
+	// SecretMask replaces the values of fields with secret:"true" tag
+	SecretMask = "******"
+)
+
+// dumpNode is a field in the dumped configuration tree
+type dumpNode struct {
+	name     string
+	value    interface{}
+	comment  string
+	children []*dumpNode // nil if the field is not a nested structure
+}
+
+// commentFunc returns the comment of a field by its Go path
+type commentFunc func(path string, f reflect.StructField) string
+
+// treeDumper converts structure values to trees of dumpNode
+type treeDumper struct {
+	tag     string      // json, yaml or toml
+	masked  bool        // secret values are masked
+	comment commentFunc // nil if no comment
+}
+
+// Dump writes the configuration values of given structure pointer which is
+// loaded by the last Load of the Loader, and every value is annotated with
+// its origin, see DumpWithProvenance and Loader.Explain
+func (this *Loader) Dump(w io.Writer, i interface{}, format string) error {
+	provenance, err := this.Explain(i)
+	if err != nil {
+		return err
+	}
+	return DumpWithProvenance(w, i, provenance, format)
+}
+
+// DumpValues writes the configuration values of given structure pointer in
+// JSON, Yaml, Properties or environment variable format without origins. The
+// values of fields with secret:"true" tag, or nested in a structure with the
+// tag, are masked
+func DumpValues(w io.Writer, i interface{}, format string) error {
+	return dump(w, i, format, nil)
+}
+
+// DumpWithProvenance writes the configuration values like DumpValues, and
+// every value is annotated with its origin in the provenance returned by
+// Loader.Explain or Loader.LoadWithProvenance. In JSON every value is written
+// as an object with value and source, since JSON has no comments
+func DumpWithProvenance(w io.Writer, i interface{}, provenance Provenance,
+	format string) error {
+	origins := make(map[string]Origin, len(provenance))
+	for _, f := range provenance {
+		origins[f.Path] = f.Origin
+	}
+
+	return dump(w, i, format, func(path string, f reflect.StructField) string {
+		if isStructField(f) {
+			return ""
+		}
+		return lookupOrigin(origins, path).String()
+	})
+}
+
+// dump writes the configuration values with the comments of fields, the
+// values are not annotated if comment is nil
+func dump(w io.Writer, i interface{}, format string,
+	comment commentFunc) error {
+	valueOfStruct, err := structValueOf(i)
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case JSONConfigType:
+		dumper := &treeDumper{tag: "json", masked: true, comment: comment}
+		return writeJSON(w, dumper.nodes(valueOfStruct, "", false),
+			comment != nil)
+	case YamlConfigType:
+		dumper := &treeDumper{tag: "yaml", masked: true, comment: comment}
+		return writeYaml(w, dumper.nodes(valueOfStruct, "", false), false)
+	case PropConfigType, EnvFormat:
+		return writeLines(w, valueOfStruct, format, func(f *field) string {
+			if comment == nil {
+				return ""
+			}
+			return comment(f.path, f.field)
+		}, true)
+	}
+
+	return fmt.Errorf("Can't support dump format: %s", format)
+}
+
+// isSecret checks if a field is tagged with secret:"true"
+func isSecret(f reflect.StructField) bool {
+	secret, _ := strconv.ParseBool(f.Tag.Get("secret"))
+	return secret
+}
+
+// hasSecrets checks if the elements of a slice, array or map type have
+// secret fields, the nested structures are checked as well
+func hasSecrets(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return holdsSecrets(t.Elem(), make(map[reflect.Type]bool))
+	}
+	return false
+}
+
+// holdsSecrets checks if the structure which type refers to has secret
+// fields, the visited types are skipped for recursive types
+func holdsSecrets(t reflect.Type, visited map[reflect.Type]bool) bool {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice ||
+		t.Kind() == reflect.Array || t.Kind() == reflect.Map {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct || visited[t] {
+		return false
+	}
+	visited[t] = true
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath == "" && (isSecret(f) || holdsSecrets(f.Type, visited)) {
+			return true
+		}
+	}
+	return false
+}
+
+// secretFields tracks the secret fields visited by walkFields, the fields
+// nested in a secret structure are secret as well
+type secretFields map[string]bool
+
+// check checks if a field is secret and records it, a slice, array or map
+// is secret as a whole if its elements have secret fields
+func (this secretFields) check(f *field) bool {
+	secret := isSecret(f.field) || hasSecrets(f.field.Type)
+	if index := strings.LastIndex(f.path, "."); index >= 0 {
+		secret = secret || this[f.path[:index]]
+	}
+	if secret {
+		this[f.path] = true
+	}
+	return secret
+}
+
+// nodes returns the fields of structure value, the structure is nested in a
+// secret one if secret is true
+func (this *treeDumper) nodes(v reflect.Value, prefix string,
+	secret bool) []*dumpNode {
+	var nodes []*dumpNode
+	for i := 0; i < v.NumField(); i++ {
+		valueOfField := v.Field(i)
+		structOfField := v.Type().Field(i)
+		if structOfField.PkgPath != "" {
+			continue
+		}
+
+		name, ok := treeName(structOfField, this.tag)
+		if !ok {
+			continue
+		}
+
+		path := joinPath(prefix, structOfField.Name, ".")
+		if valueOfField.Kind() == reflect.Ptr && !valueOfField.IsNil() {
+			valueOfField = valueOfField.Elem()
+		}
+
+		isSecret := secret || isSecret(structOfField)
+		if valueOfField.Kind() == reflect.Struct {
+			children := this.nodes(valueOfField, path, isSecret)
+			if name == "" {
+				nodes = append(nodes, children...)
+			} else {
+				nodes = append(nodes, &dumpNode{name: name,
+					comment:  this.commentOf(path, structOfField),
+					children: append([]*dumpNode{}, children...)})
+			}
+			continue
+		}
+
+		node := &dumpNode{name: name, value: valueOfField.Interface(),
+			comment: this.commentOf(path, structOfField)}
+		if (isSecret || hasSecrets(structOfField.Type)) && this.masked {
+			node.value = SecretMask
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
+// commentOf returns the comment of a field
+func (this *treeDumper) commentOf(path string, f reflect.StructField) string {
+	if this.comment == nil {
+		return ""
+	}
+	return this.comment(path, f)
+}
+
+// writeJSON writes the nodes as a JSON object, the values are annotated with
+// their comments as source if annotated is true
+func writeJSON(w io.Writer, nodes []*dumpNode, annotated bool) error {
+	var buf bytes.Buffer
+	writeJSONNodes(&buf, nodes, "", annotated)
+	buf.WriteString("\n")
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// writeJSONNodes writes the nodes as a JSON object with indent
+func writeJSONNodes(buf *bytes.Buffer, nodes []*dumpNode, indent string,
+	annotated bool) {
+	if len(nodes) == 0 {
+		buf.WriteString("{}")
+		return
+	}
+
+	buf.WriteString("{\n")
+	for i, node := range nodes {
+		name, _ := json.Marshal(node.name)
+		buf.WriteString(indent + "  " + string(name) + ": ")
+		if node.children != nil {
+			writeJSONNodes(buf, node.children, indent+"  ", annotated)
+		} else {
+			value, err := json.Marshal(node.value)
+			if err != nil {
+				value, _ = json.Marshal(fmt.Sprint(node.value))
+			}
+
+			if annotated {
+				source, _ := json.Marshal(node.comment)
+				fmt.Fprintf(buf, "{\"value\": %s, \"source\": %s}", value,
+					source)
+			} else {
+				buf.Write(value)
+			}
+		}
+
+		if i < len(nodes)-1 {
+			buf.WriteString(",")
+		}
+		buf.WriteString("\n")
+	}
+	buf.WriteString(indent + "}")
+}
+
+// writeYaml writes the nodes as Yaml, the comments are written above keys if
+// head is true, otherwise they follow values
+func writeYaml(w io.Writer, nodes []*dumpNode, head bool) error {
+	var buf bytes.Buffer
+	if err := writeYamlNodes(&buf, nodes, "", head); err != nil {
+		return err
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// writeYamlNodes writes the nodes as a Yaml mapping with indent, the values
+// are encoded by yaml.Marshal
+func writeYamlNodes(buf *bytes.Buffer, nodes []*dumpNode, indent string,
+	head bool) error {
+	if len(nodes) == 0 && indent == "" {
+		buf.WriteString("{}\n")
+		return nil
+	}
+
+	for _, node := range nodes {
+		key, err := yamlScalar(node.name)
+		if err != nil {
+			return err
+		}
+
+		comment := ""
+		if node.comment != "" {
+			if head {
+				buf.WriteString(indent + "# " + node.comment + "\n")
+			} else {
+				comment = " # " + node.comment
+			}
+		}
+
+		if node.children != nil {
+			if len(node.children) == 0 {
+				buf.WriteString(indent + key + ": {}" + comment + "\n")
+				continue
+			}
+
+			buf.WriteString(indent + key + ":" + comment + "\n")
+			if err := writeYamlNodes(buf, node.children, indent+"  ",
+				head); err != nil {
+				return err
+			}
+			continue
+		}
+
+		raw, err := yaml.Marshal(node.value)
+		if err != nil {
+			return fmt.Errorf("%s: %s", node.name, err.Error())
+		}
+
+		lines := strings.Split(strings.TrimSuffix(string(raw), "\n"), "\n")
+		if isBlockCollection(node.value) {
+			// sequences and mappings begin on the next line
+			buf.WriteString(indent + key + ":" + comment + "\n")
+			for _, line := range lines {
+				buf.WriteString(indent + "  " + line + "\n")
+			}
+			continue
+		}
+
+		// the lines of a block scalar are indented by yaml.Marshal
+		buf.WriteString(indent + key + ": " + lines[0] + comment + "\n")
+		for _, line := range lines[1:] {
+			buf.WriteString(indent + line + "\n")
+		}
+	}
+	return nil
+}
+
+// yamlScalar encodes a string as a Yaml scalar, it is quoted if needed
+func yamlScalar(s string) (string, error) {
+	raw, err := yaml.Marshal(s)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(string(raw), "\n"), nil
+}
+
+// isBlockCollection checks if yaml.Marshal encodes a value as a block
+// sequence or mapping, that is a non-empty slice, array, map or structure
+func isBlockCollection(value interface{}) bool {
+	v := reflect.ValueOf(value)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return false
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return v.Len() > 0
+	case reflect.Struct:
+		return true
+	}
+	return false
+}
+
+// writeLines writes the fields which define prop or env tag as Properties or
+// environment variable lines, every line follows a line of its comment. The
+// secret values are masked if masked is true
+func writeLines(w io.Writer, v reflect.Value, format string,
+	comment func(f *field) string, masked bool) error {
+	tag, assign := "prop", " = "
+	if format == EnvFormat {
+		tag, assign = "env", "="
+	}
+
+	var buf bytes.Buffer
+	secrets := make(secretFields)
+	walkFields(v, "", func(f *field) error {
+		secret := secrets.check(f)
+		name := f.names[tag]
+		if name == "" || isStructField(f.field) {
+			return nil
+		}
+
+		value := SecretMask
+		if !secret || !masked {
+			value = flatValue(f)
+		}
+		if format == EnvFormat {
+			value = envValue(value)
+		} else {
+			value = propValue(value)
+		}
+
+		if c := comment(f); c != "" {
+			fmt.Fprintf(&buf, "# %s\n", c)
+		}
+		fmt.Fprintf(&buf, "%s%s%s\n", name, assign, value)
+		return nil
+	})
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// flatValue returns the string of a field value, the elements of a slice are
+// joined by the separator tag of field, ':' by default
+func flatValue(f *field) string {
+	v := f.value
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+
+	if v.Kind() == reflect.Slice {
+		sp, ok := f.field.Tag.Lookup("separator")
+		if !ok {
+			sp = ":"
+		}
+
+		elements := make([]string, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			elements[i] = fmt.Sprint(v.Index(i).Interface())
+		}
+		return strings.Join(elements, sp)
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// propValue escapes the line breaks and leading spaces of a Properties value
+func propValue(value string) string {
+	value = strings.NewReplacer("\\", "\\\\", "\n", "\\n", "\r", "\\r",
+		"\t", "\\t").Replace(value)
+	if strings.HasPrefix(value, " ") {
+		value = "\\" + value
+	}
+	return value
+}
+
+// envValueEscaper escapes a double quoted value of dotenv file
+var envValueEscaper = strings.NewReplacer("\\", "\\\\", "\"", "\\\"",
+	"$", "\\$", "\n", "\\n", "\r", "\\r", "\t", "\\t")
+
+// envValue double quotes an environment variable value if it contains spaces,
+// quotes, '#', '$', backslashes or line breaks. Only the escapes supported by
+// dotenv files are used, so the value could be read back by env.ParseFile
+func envValue(value string) string {
+	if strings.ContainsAny(value, " \t\r\n\"'#$\\") {
+		return "\"" + envValueEscaper.Replace(value) + "\""
+	}
+	return value
+}
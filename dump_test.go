@@ -0,0 +1,257 @@
+/*
+ * Copyright (C) 2017 eschao <esc.chao@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/eschao/config/env"
+	"github.com/eschao/config/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func loadDumpConfig(t *testing.T) (*test.DBConfig, Provenance, string) {
+	file := filepath.Join(t.TempDir(), "db.yaml")
+	assert.NoError(t, os.WriteFile(file, []byte("dbHost: localhost\n"+
+		"dbPassword: s3cret\nlog:\n  path: /var/log/db\n"), 0644))
+
+	os.Setenv("PORT", "5432")
+	defer os.Unsetenv("PORT")
+
+	conf := &test.DBConfig{}
+	loader := New(WithSources(FileSource, EnvSource), WithConfigFile(file),
+		WithArgs("app", nil))
+	provenance, err := loader.LoadWithProvenance(conf)
+	assert.NoError(t, err)
+	return conf, provenance, file
+}
+
+func TestDumpYaml(t *testing.T) {
+	conf, provenance, file := loadDumpConfig(t)
+
+	assert := assert.New(t)
+	var buf bytes.Buffer
+	assert.NoError(DumpWithProvenance(&buf, conf, provenance, YamlConfigType))
+	assert.Equal("dbHost: localhost # file "+file+":1:1 (dbHost)\n"+
+		"dbPort: 5432 # env PORT\n"+
+		"dbUser: \"\" # not set\n"+
+		"dbPassword: '******' # file "+file+":2:1 (dbPassword)\n"+
+		"log:\n"+
+		"  path: /var/log/db # file "+file+":4:3 (log.path)\n"+
+		"  level: \"\" # not set\n", buf.String())
+
+	// values are not annotated without provenance
+	buf.Reset()
+	assert.NoError(DumpValues(&buf, conf, YamlConfigType))
+	assert.Equal("dbHost: localhost\ndbPort: 5432\ndbUser: \"\"\n"+
+		"dbPassword: '******'\nlog:\n  path: /var/log/db\n  level: \"\"\n",
+		buf.String())
+}
+
+func TestDumpJSON(t *testing.T) {
+	conf, provenance, file := loadDumpConfig(t)
+
+	assert := assert.New(t)
+	var buf bytes.Buffer
+	assert.NoError(DumpWithProvenance(&buf, conf, provenance, JSONConfigType))
+	assert.Equal("{\n"+
+		"  \"dbHost\": {\"value\": \"localhost\", \"source\": \"file "+file+
+		":1:1 (dbHost)\"},\n"+
+		"  \"dbPort\": {\"value\": 5432, \"source\": \"env PORT\"},\n"+
+		"  \"dbUser\": {\"value\": \"\", \"source\": \"not set\"},\n"+
+		"  \"dbPassword\": {\"value\": \"******\", \"source\": \"file "+file+
+		":2:1 (dbPassword)\"},\n"+
+		"  \"log\": {\n"+
+		"    \"path\": {\"value\": \"/var/log/db\", \"source\": \"file "+file+
+		":4:3 (log.path)\"},\n"+
+		"    \"level\": {\"value\": \"\", \"source\": \"not set\"}\n"+
+		"  }\n"+
+		"}\n", buf.String())
+
+	buf.Reset()
+	assert.NoError(DumpValues(&buf, conf, JSONConfigType))
+	assert.Contains(buf.String(), "  \"dbPort\": 5432,\n")
+	assert.NotContains(buf.String(), "source")
+}
+
+func TestDumpLines(t *testing.T) {
+	conf, provenance, file := loadDumpConfig(t)
+
+	assert := assert.New(t)
+	var buf bytes.Buffer
+	assert.NoError(DumpWithProvenance(&buf, conf, provenance, PropConfigType))
+	assert.Contains(buf.String(), "# env PORT\ndbPort = 5432\n")
+	assert.Contains(buf.String(), "# file "+file+
+		":2:1 (dbPassword)\ndbPassword = ******\n")
+	assert.Contains(buf.String(), "log.path = /var/log/db\n")
+	assert.NotContains(buf.String(), "s3cret")
+
+	buf.Reset()
+	assert.NoError(DumpWithProvenance(&buf, conf, provenance, EnvFormat))
+	assert.Contains(buf.String(), "# env PORT\nPORT=5432\n")
+	assert.Contains(buf.String(), "PASSWORD=******\n")
+	assert.Contains(buf.String(), "LOG_PATH=/var/log/db\n")
+	assert.NotContains(buf.String(), "s3cret")
+
+	conf.Host = "my host"
+	buf.Reset()
+	assert.NoError(DumpValues(&buf, conf, EnvFormat))
+	assert.Contains(buf.String(), "HOST=\"my host\"\n")
+
+	assert.Error(DumpValues(&buf, conf, "xml"))
+	assert.Error(DumpValues(&buf, *conf, YamlConfigType))
+}
+
+func TestDumpEnvRoundTrip(t *testing.T) {
+	assert := assert.New(t)
+	conf := test.DBConfig{
+		Host: "a$HOME b",
+		User: "say \"hi\" to 世界 \\ ${USER}",
+		Log:  test.LogConfig{Path: "line1\nline2\t'#"},
+	}
+
+	var buf bytes.Buffer
+	assert.NoError(DumpValues(&buf, &conf, EnvFormat))
+	file := filepath.Join(t.TempDir(), "db.env")
+	assert.NoError(os.WriteFile(file, buf.Bytes(), 0644))
+
+	loaded := test.DBConfig{}
+	assert.NoError(env.ParseFile(&loaded, file, ""))
+	assert.Equal(conf.Host, loaded.Host)
+	assert.Equal(conf.User, loaded.User)
+	assert.Equal(conf.Log.Path, loaded.Log.Path)
+}
+
+func TestLoaderDump(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "db.yaml")
+	assert.NoError(t, os.WriteFile(file, []byte("dbHost: localhost\n"+
+		"dbPassword: s3cret\n"), 0644))
+
+	assert := assert.New(t)
+	conf := test.DBConfig{}
+	loader := New(WithSources(FileSource), WithConfigFile(file))
+	var buf bytes.Buffer
+	assert.Error(loader.Dump(&buf, &conf, YamlConfigType))
+
+	assert.NoError(loader.Load(&conf))
+	assert.NoError(loader.Dump(&buf, &conf, YamlConfigType))
+	assert.Equal("dbHost: localhost # file "+file+":1:1 (dbHost)\n"+
+		"dbPort: 0 # not set\n"+
+		"dbUser: \"\" # not set\n"+
+		"dbPassword: '******' # file "+file+":2:1 (dbPassword)\n"+
+		"log:\n"+
+		"  path: \"\" # not set\n"+
+		"  level: \"\" # not set\n", buf.String())
+}
+
+func TestDumpSecretStruct(t *testing.T) {
+	conf := struct {
+		Login test.LoginConfig `json:"login" env:"LOGIN_"`
+		DB    test.DBConfig    `json:"db" env:"DB_" secret:"true"`
+	}{}
+	conf.Login.Password = "login-pass"
+	conf.DB.Host = "db-host"
+
+	assert := assert.New(t)
+	var buf bytes.Buffer
+	assert.NoError(DumpValues(&buf, &conf, EnvFormat))
+	assert.Contains(buf.String(), "LOGIN_PASSWORD=******\n")
+	assert.Contains(buf.String(), "DB_HOST=******\n")
+	assert.NotContains(buf.String(), "login-pass")
+	assert.NotContains(buf.String(), "db-host")
+
+	provenance, err := New(WithSources()).LoadWithProvenance(&conf)
+	assert.NoError(err)
+	assert.NotContains(provenance.String(), "login-pass")
+	assert.NotContains(provenance.String(), "db-host")
+	assert.Contains(provenance.String(), "DB.Log.Path = ****** <- not set")
+}
+
+func TestDumpSecretContainers(t *testing.T) {
+	type cred struct {
+		User string `json:"user" yaml:"user"`
+		Pass string `json:"pass" yaml:"pass" secret:"true"`
+	}
+	type node struct {
+		Name  string  `json:"name" yaml:"name"`
+		Nodes []*node `json:"nodes" yaml:"nodes"`
+		Creds [2]cred `json:"creds" yaml:"creds"`
+	}
+	conf := struct {
+		Creds []cred            `json:"creds" yaml:"creds" prop:"creds" env:"CREDS"`
+		M     map[string]cred   `json:"m"     yaml:"m"     prop:"m"     env:"M"`
+		Nodes []node            `json:"nodes" yaml:"nodes" prop:"nodes" env:"NODES"`
+		Tags  map[string]string `json:"tags"  yaml:"tags"  prop:"tags"  env:"TAGS"`
+	}{
+		Creds: []cred{{User: "admin", Pass: "leak1"}},
+		M:     map[string]cred{"db": {User: "root", Pass: "leak2"}},
+		Nodes: []node{{Nodes: []*node{{Creds: [2]cred{{Pass: "leak3"}}}}}},
+		Tags:  map[string]string{"env": "prod"},
+	}
+
+	assert := assert.New(t)
+	for _, format := range []string{YamlConfigType, JSONConfigType,
+		PropConfigType, EnvFormat} {
+		var buf bytes.Buffer
+		assert.NoError(DumpValues(&buf, &conf, format), format)
+		assert.NotContains(buf.String(), "leak", format)
+		assert.Contains(buf.String(), "prod", format)
+	}
+
+	provenance, err := New(WithSources()).LoadWithProvenance(&conf)
+	assert.NoError(err)
+	assert.NotContains(provenance.String(), "leak")
+	assert.Contains(provenance.String(), "Creds = ******")
+}
+
+func TestDumpYamlValues(t *testing.T) {
+	type values struct {
+		Text   string            `yaml:"text"`
+		Yes    string            `yaml:"yes"`
+		Hosts  []string          `yaml:"hosts"`
+		Empty  []string          `yaml:"empty"`
+		Labels map[string]string `yaml:"labels"`
+		Ptr    *int              `yaml:"ptr"`
+		Log    test.LogConfig    `yaml:"log"`
+	}
+	type config struct {
+		Values values `yaml:"values"`
+	}
+	conf := config{Values: values{
+		Text:   "line 1\nline 2: x\n",
+		Yes:    "yes",
+		Hosts:  []string{"a", "b #c"},
+		Empty:  []string{},
+		Labels: map[string]string{"key": "value"},
+		Log:    test.LogConfig{Path: "/tmp"},
+	}}
+
+	assert := assert.New(t)
+	var buf bytes.Buffer
+	assert.NoError(DumpValues(&buf, &conf, YamlConfigType))
+
+	parsed := config{}
+	assert.NoError(ParseBytes(&parsed, buf.Bytes(), YamlConfigType),
+		buf.String())
+	assert.Equal(conf, parsed, buf.String())
+
+	buf.Reset()
+	assert.NoError(DumpValues(&buf, &struct{}{}, YamlConfigType))
+	assert.Equal("{}\n", buf.String())
+}
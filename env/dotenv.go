@@ -0,0 +1,249 @@
+/*
+ * Copyright (C) 2017 eschao <esc.chao@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package env
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// DefaultDotenvFile is the dotenv file loaded by LoadDotenv if no file given
+const DefaultDotenvFile = ".env"
+
+// LoadDotenv reads the given dotenv files in order and sets their variables
+// into process environment. The existing environment variables are not
+// overridden. If no file is given, .env in current directory is loaded
+func LoadDotenv(paths ...string) error {
+	if len(paths) == 0 {
+		paths = []string{DefaultDotenvFile}
+	}
+
+	for _, path := range paths {
+		vars, err := readDotenvFile(path, os.LookupEnv)
+		if err != nil {
+			return err
+		}
+
+		for name, value := range vars {
+			if _, ok := os.LookupEnv(name); ok {
+				continue
+			}
+			if err := os.Setenv(name, value); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// ParseFile parses given structure interface with the variables defined in a
+// dotenv file and the environment name prefix, like ParseWith. The variables
+// are only resolved from the file and the process environment is untouched
+func ParseFile(i interface{}, path string, prefix string) error {
+	vars, err := readDotenvFile(path, os.LookupEnv)
+	if err != nil {
+		return err
+	}
+
+	return parseWith(i, prefix, func(name string) (string, bool) {
+		value, ok := vars[name]
+		return value, ok
+	})
+}
+
+// readDotenvFile reads variables from a dotenv file, the lookup is used to
+// expand variables which are not defined in the file
+func readDotenvFile(path string, lookup lookupFunc) (map[string]string,
+	error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Can't open dotenv file. %s", err.Error())
+	}
+
+	vars, err := readDotenv(string(raw), lookup)
+	if err != nil {
+		return nil, fmt.Errorf("Can't parse dotenv file %s. %s", path,
+			err.Error())
+	}
+	return vars, nil
+}
+
+// readDotenv reads variables from dotenv content. It supports:
+//   - comment lines and trailing comments beginning with '#'
+//   - optional 'export' prefix
+//   - single quoted values which are kept as they are
+//   - double quoted values with escapes: \n, \r, \t, \", \\, \$ and newlines
+//   - ${VAR} and $VAR expansion in double quoted and unquoted values. The
+//     variable is resolved from the former ones in the content first, then
+//     the lookup function
+func readDotenv(content string, lookup lookupFunc) (map[string]string,
+	error) {
+	vars := make(map[string]string)
+	expand := func(name string) string {
+		if value, ok := vars[name]; ok {
+			return value
+		}
+		if value, ok := lookup(name); ok {
+			return value
+		}
+		return ""
+	}
+
+	content = strings.Replace(content, "\r\n", "\n", -1)
+	lineNo := 0
+	for len(content) > 0 {
+		lineNo++
+		line := content
+		if index := strings.IndexByte(content, '\n'); index >= 0 {
+			line = content[:index]
+			content = content[index+1:]
+		} else {
+			content = ""
+		}
+
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		if strings.HasPrefix(line, "export ") ||
+			strings.HasPrefix(line, "export\t") {
+			line = strings.TrimSpace(line[len("export"):])
+		}
+
+		index := strings.IndexByte(line, '=')
+		if index <= 0 {
+			return nil, fmt.Errorf("line %d: invalid variable: %s", lineNo, line)
+		}
+
+		name := strings.TrimSpace(line[:index])
+		value := strings.TrimLeft(line[index+1:], " \t")
+		startLineNo := lineNo
+
+		switch {
+		case strings.HasPrefix(value, "'"):
+			end := strings.IndexByte(value[1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated quoted value",
+					startLineNo)
+			}
+			value = value[1 : end+1]
+		case strings.HasPrefix(value, "\""):
+			// double quoted value could span multiple lines
+			value = value[1:]
+			for closingQuote(value) < 0 {
+				if content == "" {
+					return nil, fmt.Errorf("line %d: unterminated quoted value",
+						startLineNo)
+				}
+				lineNo++
+				next := content
+				if index := strings.IndexByte(content, '\n'); index >= 0 {
+					next = content[:index]
+					content = content[index+1:]
+				} else {
+					content = ""
+				}
+				value += "\n" + next
+			}
+			value = expandValue(value[:closingQuote(value)], expand, true)
+		default:
+			if index := strings.Index(value, " #"); index >= 0 {
+				value = value[:index]
+			}
+			value = expandValue(strings.TrimSpace(value), expand, false)
+		}
+
+		vars[name] = value
+	}
+
+	return vars, nil
+}
+
+// closingQuote returns index of the unescaped double quote in s, or -1
+func closingQuote(s string) int {
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' {
+			i++
+		} else if s[i] == '"' {
+			return i
+		}
+	}
+	return -1
+}
+
+// expandValue expands ${VAR} and $VAR in the value, and handles escapes if
+// the value is double quoted
+func expandValue(s string, expand func(string) string, escape bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if escape && c == '\\' && i+1 < len(s) {
+			i++
+			switch s[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case '"', '\\', '$':
+				b.WriteByte(s[i])
+			default:
+				b.WriteByte('\\')
+				b.WriteByte(s[i])
+			}
+			continue
+		}
+
+		if c != '$' || i+1 >= len(s) {
+			b.WriteByte(c)
+			continue
+		}
+
+		if s[i+1] == '{' {
+			end := strings.IndexByte(s[i+2:], '}')
+			if end < 0 {
+				b.WriteByte(c)
+				continue
+			}
+			b.WriteString(expand(s[i+2 : i+2+end]))
+			i += end + 2
+			continue
+		}
+
+		end := i + 1
+		for end < len(s) && isNameChar(s[end]) {
+			end++
+		}
+		if end == i+1 {
+			b.WriteByte(c)
+			continue
+		}
+		b.WriteString(expand(s[i+1 : end]))
+		i = end - 1
+	}
+
+	return b.String()
+}
+
+func isNameChar(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') ||
+		(c >= '0' && c <= '9')
+}
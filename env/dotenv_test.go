@@ -0,0 +1,117 @@
+/*
+ * Copyright (C) 2017 eschao <esc.chao@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package env
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/eschao/config/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadDotenv(t *testing.T) {
+	content := `
+# comment
+export NAME=test-name
+PLAIN = plain value # comment
+SINGLE='single ${NAME} \n'
+DOUBLE="double ${NAME}\t\"quoted\" \$NAME"
+MULTI="first line
+second line"
+EXPAND=$NAME/${HOME_DIR}/$UNKNOWN
+EMPTY=
+`
+	lookup := func(name string) (string, bool) {
+		if name == "HOME_DIR" {
+			return "/home/test", true
+		}
+		return "", false
+	}
+
+	assert := assert.New(t)
+	vars, err := readDotenv(content, lookup)
+	assert.NoError(err)
+	assert.Equal(map[string]string{
+		"NAME":   "test-name",
+		"PLAIN":  "plain value",
+		"SINGLE": `single ${NAME} \n`,
+		"DOUBLE": "double test-name\t\"quoted\" $NAME",
+		"MULTI":  "first line\nsecond line",
+		"EXPAND": "test-name//home/test/",
+		"EMPTY":  "",
+	}, vars)
+
+	_, err = readDotenv("NAME", lookup)
+	assert.Error(err)
+	_, err = readDotenv("NAME=\"unterminated\nvalue", lookup)
+	assert.Error(err)
+	_, err = readDotenv("NAME='unterminated", lookup)
+	assert.Error(err)
+}
+
+func TestParseFile(t *testing.T) {
+	_, curTestFile, _, _ := runtime.Caller(0)
+	path := filepath.Join(filepath.Dir(curTestFile), "../test/test.env")
+
+	os.Setenv("CONFIG_TEST_DOTENV_LOG_ROOT", "/var/log")
+	defer os.Unsetenv("CONFIG_TEST_DOTENV_LOG_ROOT")
+
+	assert := assert.New(t)
+	conf := test.DBConfig{}
+	assert.NoError(ParseFile(&conf, path, "CONFIG_TEST_DOTENV_DB_"))
+	assert.Equal(DB_HOST, conf.Host)
+	assert.Equal(DB_PORT, conf.Port)
+	assert.Equal(DB_USER, conf.User)
+	assert.Equal(DB_PASSWORD, conf.Password)
+	assert.Equal(DB_LOG_PATH, conf.Log.Path)
+	assert.Equal(DB_LOG_LEVEL, conf.Log.Level)
+
+	// process environment is untouched
+	_, ok := os.LookupEnv("CONFIG_TEST_DOTENV_DB_HOST")
+	assert.False(ok)
+
+	assert.Error(ParseFile(&conf, "not-exist.env", ""))
+}
+
+func TestLoadDotenv(t *testing.T) {
+	_, curTestFile, _, _ := runtime.Caller(0)
+	path := filepath.Join(filepath.Dir(curTestFile), "../test/test.env")
+
+	prefix := "CONFIG_TEST_DOTENV_DB_"
+	os.Setenv(prefix+"USER", "env-user")
+	defer func() {
+		for _, name := range []string{"HOST", "PORT", "USER", "PASSWORD",
+			"LOG_PATH", "LOG_LEVEL"} {
+			os.Unsetenv(prefix + name)
+		}
+	}()
+
+	assert := assert.New(t)
+	assert.NoError(LoadDotenv(path))
+
+	conf := test.DBConfig{}
+	assert.NoError(ParseWith(&conf, prefix))
+	assert.Equal(DB_HOST, conf.Host)
+	assert.Equal(DB_PORT, conf.Port)
+	// existing environment variable is not overridden
+	assert.Equal("env-user", conf.User)
+	assert.Equal("/db", conf.Log.Path)
+
+	assert.Error(LoadDotenv("not-exist.env"))
+}
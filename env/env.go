@@ -17,12 +17,20 @@ package env
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
 	"reflect"
+	"sort"
+	"strings"
 
 	"github.com/eschao/config/utils"
 )
 
+// FileSuffix is the suffix of environment variable which refers to a file
+// containing the value, e.g: DB_PASSWORD_FILE=/run/secrets/db_password is
+// used if DB_PASSWORD is not set
+const FileSuffix = "_FILE"
+
 // Parse parses given structure interface, extracts environment definitions
 // from its tag and sets structure with defined environement variables
 func Parse(i interface{}) error {
@@ -43,6 +51,85 @@ func Parse(i interface{}) error {
 // The Server.DB.Host will be mapped to environment variable: DB_HOST which is
 // concatenated from DB tag in Server struct and Host tag in Database struct
 func ParseWith(i interface{}, prefix string) error {
+	return parseWith(i, prefix, os.LookupEnv)
+}
+
+// ParseStrict parses like ParseWith, but reports the environment variables
+// which begin with the prefix but match no field, e.g: APP_DB_HOTS is
+// unknown with prefix APP_ if there is no APP_DB_HOTS field. The variables
+// are not checked if the prefix is empty, since the env tags of nested
+// structures like LOG_ could be shared by unrelated variables. The variables
+// of fields with _FILE suffix are not unknown
+func ParseStrict(i interface{}, prefix string) error {
+	if err := ParseWith(i, prefix); err != nil {
+		return err
+	}
+
+	unknown := unknownEnvs(reflect.TypeOf(i).Elem(), prefix, os.Environ())
+	if len(unknown) > 0 {
+		return fmt.Errorf("Unknown environment variables: %s",
+			strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// unknownEnvs returns the names of environment variables which begin with the
+// non-empty prefix but match no field, the environ is in the form of
+// NAME=value like os.Environ
+func unknownEnvs(t reflect.Type, prefix string, environ []string) []string {
+	if prefix == "" {
+		return nil
+	}
+
+	names := make(map[string]bool)
+	envNames(t, prefix, names)
+
+	var unknown []string
+	for _, env := range environ {
+		name := env
+		if index := strings.Index(env, "="); index >= 0 {
+			name = env[:index]
+		}
+
+		if names[name] || (strings.HasSuffix(name, FileSuffix) &&
+			names[strings.TrimSuffix(name, FileSuffix)]) {
+			continue
+		}
+
+		if strings.HasPrefix(name, prefix) {
+			unknown = append(unknown, name)
+		}
+	}
+
+	sort.Strings(unknown)
+	return unknown
+}
+
+// envNames collects the environment variable names of fields, the nested
+// structures of nil pointers are included
+func envNames(t reflect.Type, prefix string, names map[string]bool) {
+	for i := 0; i < t.NumField(); i++ {
+		structOfField := t.Field(i)
+		envName := structOfField.Tag.Get("env")
+		typeOfField := structOfField.Type
+		if typeOfField.Kind() == reflect.Ptr {
+			typeOfField = typeOfField.Elem()
+		}
+
+		if typeOfField.Kind() == reflect.Struct {
+			envNames(typeOfField, prefix+envName, names)
+		} else if envName != "" {
+			names[prefix+envName] = true
+		}
+	}
+}
+
+// lookupFunc looks up the value of an environment variable by its name
+type lookupFunc func(name string) (string, bool)
+
+// parseWith parses given structure interface with environment variables
+// looked up by the given function
+func parseWith(i interface{}, prefix string, lookup lookupFunc) error {
 	ptrRef := reflect.ValueOf(i)
 
 	if ptrRef.IsNil() || ptrRef.Kind() != reflect.Ptr {
@@ -56,11 +143,11 @@ func ParseWith(i interface{}, prefix string) error {
 			valueOfStruct.Kind().String())
 	}
 
-	return parseValue(valueOfStruct, prefix)
+	return parseValue(valueOfStruct, prefix, lookup)
 }
 
 // parseValue parses a reflect.Value object
-func parseValue(v reflect.Value, prefix string) error {
+func parseValue(v reflect.Value, prefix string, lookup lookupFunc) error {
 	typeOfStruct := v.Type()
 	var err error
 	for i := 0; i < v.NumField() && err == nil; i++ {
@@ -71,16 +158,17 @@ func parseValue(v reflect.Value, prefix string) error {
 		// recursively unmarshal if value is ptr type
 		if kindOfField == reflect.Ptr {
 			if !valueOfField.IsNil() && valueOfField.CanSet() {
-				err = ParseWith(valueOfField.Interface(),
-					prefix+structOfField.Tag.Get("env"))
-			} else {
-				continue
+				err = parseWith(valueOfField.Interface(),
+					prefix+structOfField.Tag.Get("env"), lookup)
 			}
+			continue
 		} else if kindOfField == reflect.Struct {
-			err = parseValue(valueOfField, prefix+structOfField.Tag.Get("env"))
+			err = parseValue(valueOfField, prefix+structOfField.Tag.Get("env"),
+				lookup)
+			continue
 		}
 
-		err = setFieldValue(valueOfField, structOfField, prefix)
+		err = setFieldValue(valueOfField, structOfField, prefix, lookup)
 	}
 
 	return err
@@ -97,16 +185,41 @@ func getEnvValue(envName string, f reflect.StructField) (string, bool) {
 	return envValue, ok
 }
 
+// lookupFile looks up <NAME>_FILE environment variable and returns the
+// content of the file it refers to, the trailing newlines are trimmed. It
+// is used to deliver secrets by files, e.g: Docker secrets
+func lookupFile(envName string, lookup lookupFunc) (string, bool, error) {
+	path, ok := lookup(envName + FileSuffix)
+	if !ok {
+		return "", false, nil
+	}
+
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", false, fmt.Errorf("Can't read %s%s file. %s", envName,
+			FileSuffix, err.Error())
+	}
+	return strings.TrimRight(string(raw), "\r\n"), true, nil
+}
+
 // setFieldValue sets a reflect.Value with environment value
-func setFieldValue(v reflect.Value, f reflect.StructField, prefix string) error {
+func setFieldValue(v reflect.Value, f reflect.StructField, prefix string,
+	lookup lookupFunc) error {
 	envName := f.Tag.Get("env")
 	if envName == "" {
 		return nil
 	}
 
-	envValue, ok := os.LookupEnv(prefix + envName)
+	envValue, ok := lookup(prefix + envName)
 	if !ok {
-		return nil
+		var err error
+		envValue, ok, err = lookupFile(prefix+envName, lookup)
+		if err != nil {
+			return fmt.Errorf("%s: %s", f.Name, err.Error())
+		}
+		if !ok {
+			return nil
+		}
 	}
 
 	if !v.CanSet() {
@@ -17,6 +17,7 @@ package env
 
 import (
 	"os"
+	"path/filepath"
 	"strconv"
 	"testing"
 
@@ -272,3 +273,75 @@ func TestSlicesConfigEnv(t *testing.T) {
 	assert.Equal(4, conf.Values[2])
 	assert.Equal(5, conf.Values[3])
 }
+
+func TestNestedConfigWithErrorEnv(t *testing.T) {
+	os.Setenv("CONFIG_TEST_SERVICE_DB_PORT", "xxx")
+	defer os.Unsetenv("CONFIG_TEST_SERVICE_DB_PORT")
+
+	assert := assert.New(t)
+	serviceConfig := test.ServiceConfig{}
+	assert.Error(Parse(&serviceConfig))
+}
+
+func TestFileEnv(t *testing.T) {
+	dir := t.TempDir()
+	passwordFile := filepath.Join(dir, "db_password")
+	portFile := filepath.Join(dir, "db_port")
+	assert := assert.New(t)
+	assert.NoError(os.WriteFile(passwordFile, []byte(DB_PASSWORD+"\n"), 0600))
+	assert.NoError(os.WriteFile(portFile, []byte("9090\r\n"), 0600))
+
+	os.Setenv("PASSWORD_FILE", passwordFile)
+	os.Setenv("PORT_FILE", portFile)
+	os.Setenv("USER", DB_USER)
+	os.Setenv("USER_FILE", passwordFile)
+	defer os.Unsetenv("PASSWORD_FILE")
+	defer os.Unsetenv("PORT_FILE")
+	defer os.Unsetenv("USER")
+	defer os.Unsetenv("USER_FILE")
+
+	dbConfig := test.DBConfig{}
+	assert.NoError(Parse(&dbConfig))
+	assert.Equal(DB_PASSWORD, dbConfig.Password)
+	assert.Equal(DB_PORT, dbConfig.Port)
+	// variable has higher priority than the file
+	assert.Equal(DB_USER, dbConfig.User)
+
+	os.Setenv("HOST_FILE", filepath.Join(dir, "not-exist"))
+	defer os.Unsetenv("HOST_FILE")
+	assert.Error(Parse(&dbConfig))
+}
+
+func TestParseStrict(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "password")
+	assert.NoError(t, os.WriteFile(file, []byte(DB_PASSWORD), 0644))
+	os.Setenv("CONFIG_TEST_STRICT_HOST", DB_HOST)
+	os.Setenv("CONFIG_TEST_STRICT_PASSWORD_FILE", file)
+	os.Setenv("LOG_FOO", "foo")
+	defer os.Unsetenv("CONFIG_TEST_STRICT_HOST")
+	defer os.Unsetenv("CONFIG_TEST_STRICT_PASSWORD_FILE")
+	defer os.Unsetenv("LOG_FOO")
+
+	assert := assert.New(t)
+	dbConfig := test.DBConfig{}
+	assert.NoError(ParseStrict(&dbConfig, "CONFIG_TEST_STRICT_"))
+	assert.Equal(DB_HOST, dbConfig.Host)
+
+	os.Setenv("CONFIG_TEST_STRICT_HOTS", DB_HOST)
+	os.Setenv("CONFIG_TEST_STRICT_LOG_LEVLE", SERVICE_LOG_LEVEL)
+	defer os.Unsetenv("CONFIG_TEST_STRICT_HOTS")
+	defer os.Unsetenv("CONFIG_TEST_STRICT_LOG_LEVLE")
+
+	err := ParseStrict(&dbConfig, "CONFIG_TEST_STRICT_")
+	assert.Error(err)
+	assert.Equal("Unknown environment variables: CONFIG_TEST_STRICT_HOTS, "+
+		"CONFIG_TEST_STRICT_LOG_LEVLE", err.Error())
+
+	// the variables out of the prefix are not checked
+	assert.NoError(ParseStrict(&dbConfig, "CONFIG_TEST_STRICT_DB_"))
+
+	// nothing is checked without prefix, e.g: LOG_FOO of nested LOG_
+	assert.NoError(ParseStrict(&dbConfig, ""))
+	serviceConfig := test.ServiceConfig{}
+	assert.NoError(ParseStrict(&serviceConfig, ""))
+}
@@ -0,0 +1,202 @@
+/*
+ * Copyright (C) 2017 eschao <esc.chao@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package config
+
+import (
+	"reflect"
+	"strings"
+)
+
+// nameTags are the tags which define configuration names, in the order of
+// being reported
+var nameTags = []string{"json", "yaml", "toml", "prop", "ini", "env", "cli"}
+
+// field describes a structure field with its Go path and configuration
+// names in every source
+type field struct {
+	value reflect.Value
+	field reflect.StructField
+	path  string            // Go path, e.g: DBConfig.Log.Path
+	names map[string]string // configuration names keyed by tag
+}
+
+// fieldScope is the naming context of a nested structure
+type fieldScope struct {
+	path string
+	json string
+	yaml string
+	toml string
+	prop string
+	ini  string
+	env  string
+	cli  string
+}
+
+// walkFields calls fn for every exported field of the given structure value,
+// including the nested structure fields and the fields of them. The fields
+// of a nil pointer are not visited
+func walkFields(v reflect.Value, envPrefix string, fn func(*field) error) error {
+	return walkScope(v, fieldScope{env: envPrefix}, fn)
+}
+
+func walkScope(v reflect.Value, scope fieldScope,
+	fn func(*field) error) error {
+	typeOfStruct := v.Type()
+	for i := 0; i < v.NumField(); i++ {
+		valueOfField := v.Field(i)
+		structOfField := typeOfStruct.Field(i)
+		if structOfField.PkgPath != "" {
+			continue
+		}
+
+		f := &field{
+			value: valueOfField,
+			field: structOfField,
+			path:  joinPath(scope.path, structOfField.Name, "."),
+			names: scope.namesOf(structOfField),
+		}
+		if err := fn(f); err != nil {
+			return err
+		}
+
+		if valueOfField.Kind() == reflect.Ptr && !valueOfField.IsNil() {
+			valueOfField = valueOfField.Elem()
+		}
+
+		if valueOfField.Kind() == reflect.Struct {
+			if err := walkScope(valueOfField, scope.nested(structOfField),
+				fn); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// namesOf returns the configuration names of the given field which defines
+// corresponding tags
+func (this fieldScope) namesOf(f reflect.StructField) map[string]string {
+	names := make(map[string]string)
+	if name := tagName(f, "json"); name != "" {
+		names["json"] = joinPath(this.json, name, ".")
+	}
+	if name := tagName(f, "yaml"); name != "" {
+		names["yaml"] = joinPath(this.yaml, name, ".")
+	}
+	if name := tagName(f, "toml"); name != "" {
+		names["toml"] = joinPath(this.toml, name, ".")
+	}
+	if name := f.Tag.Get("prop"); name != "" {
+		names["prop"] = joinPath(this.prop, name, ".")
+	}
+	if name := f.Tag.Get("ini"); name != "" {
+		names["ini"] = joinPath(this.ini, name, ".")
+	}
+	if name := f.Tag.Get("env"); name != "" {
+		names["env"] = this.env + name
+	}
+	if name := cliName(f); name != "" {
+		// nested structure is a sub-command instead of a flag
+		if !isStructField(f) {
+			name = "-" + name
+		}
+		names["cli"] = joinPath(this.cli, name, " ")
+	}
+	return names
+}
+
+// nested returns the naming context of the given nested structure field
+func (this fieldScope) nested(f reflect.StructField) fieldScope {
+	jsonName := tagName(f, "json")
+	if jsonName == "" {
+		jsonName = f.Name
+	}
+
+	yamlName := tagName(f, "yaml")
+	if yamlName == "" {
+		yamlName = strings.ToLower(f.Name)
+	}
+
+	tomlName := tagName(f, "toml")
+	if tomlName == "" {
+		tomlName = f.Name
+	}
+
+	return fieldScope{
+		path: joinPath(this.path, f.Name, "."),
+		json: joinPath(this.json, jsonName, "."),
+		yaml: joinPath(this.yaml, yamlName, "."),
+		toml: joinPath(this.toml, tomlName, "."),
+		prop: joinPath(this.prop, f.Tag.Get("prop"), "."),
+		ini:  joinPath(this.ini, f.Tag.Get("ini"), "."),
+		env:  this.env + f.Tag.Get("env"),
+		cli:  joinPath(this.cli, cliName(f), " "),
+	}
+}
+
+// isStructField checks if the given field is a structure or a pointer to
+// structure
+func isStructField(f reflect.StructField) bool {
+	t := f.Type
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct
+}
+
+// tagName returns the name defined in a json or yaml like tag, the options
+// after ',' are dropped and "-" means no name
+func tagName(f reflect.StructField, tag string) string {
+	name := f.Tag.Get(tag)
+	if index := strings.Index(name, ","); index >= 0 {
+		name = name[:index]
+	}
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+// cliName returns the command line flag or sub-command name defined in cli
+// tag
+func cliName(f reflect.StructField) string {
+	name := f.Tag.Get("cli")
+	if index := strings.Index(name, " "); index >= 0 {
+		name = name[:index]
+	}
+	return name
+}
+
+// cliUsage returns the usage text defined in cli tag after the name
+func cliUsage(f reflect.StructField) string {
+	usage := f.Tag.Get("cli")
+	if index := strings.Index(usage, " "); index >= 0 {
+		return usage[index+1:]
+	}
+	return ""
+}
+
+// joinPath joins parent and name by separator, the empty one is ignored
+func joinPath(parent, name, separator string) string {
+	if parent == "" {
+		return name
+	}
+	if name == "" {
+		return parent
+	}
+	return parent + separator + name
+}
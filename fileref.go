@@ -0,0 +1,140 @@
+/*
+ * Copyright (C) 2017 eschao <esc.chao@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"strings"
+)
+
+const (
+	// FileRefPrefix is the prefix of a string value which refers to a file
+	// containing the value, e.g: file:///run/secrets/db_password
+	FileRefPrefix = "file://"
+
+	// AtFileRefPrefix is the short prefix of a file reference, e.g:
+	// @/run/secrets/db_password. Use @@ for a value beginning with '@'
+	AtFileRefPrefix = "@"
+)
+
+// ResolveFileRefs replaces the string values of given structure interface
+// which refer to files with the file contents, including the elements of
+// string slices and the values of string maps. The trailing newlines of
+// file contents are trimmed
+func ResolveFileRefs(i interface{}) error {
+	valueOfStruct, err := structValueOf(i)
+	if err != nil {
+		return err
+	}
+	return resolveFileRefs(valueOfStruct)
+}
+
+// resolveFileRefs replaces file references in string values of structure
+// value
+func resolveFileRefs(v reflect.Value) error {
+	return walkFields(v, "", func(f *field) error {
+		var err error
+		v := f.value
+		switch {
+		case v.Kind() == reflect.String:
+			err = setFileRef(v)
+		case v.Kind() == reflect.Slice &&
+			v.Type().Elem().Kind() == reflect.String:
+			for i := 0; i < v.Len() && err == nil; i++ {
+				err = setFileRef(v.Index(i))
+			}
+		case v.Kind() == reflect.Map && v.Type().Elem().Kind() == reflect.String:
+			for _, key := range v.MapKeys() {
+				s, e := readFileRef(v.MapIndex(key).String())
+				if e != nil {
+					err = e
+					break
+				}
+				v.SetMapIndex(key, reflect.ValueOf(s).Convert(v.Type().Elem()))
+			}
+		}
+
+		if err != nil {
+			return fmt.Errorf("%s: %s", f.path, err.Error())
+		}
+		return nil
+	})
+}
+
+// setFileRef sets string value with the file content it refers to
+func setFileRef(v reflect.Value) error {
+	s, err := readFileRef(v.String())
+	if err != nil {
+		return err
+	}
+	v.SetString(s)
+	return nil
+}
+
+// hasFileRef checks if a string value, or any element of a string slice or
+// map value is a file reference
+func hasFileRef(v reflect.Value) bool {
+	switch {
+	case v.Kind() == reflect.String:
+		return isFileRef(v.String())
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.String:
+		for i := 0; i < v.Len(); i++ {
+			if isFileRef(v.Index(i).String()) {
+				return true
+			}
+		}
+	case v.Kind() == reflect.Map && v.Type().Elem().Kind() == reflect.String:
+		for _, key := range v.MapKeys() {
+			if isFileRef(v.MapIndex(key).String()) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isFileRef checks if the value refers to a file, @@ is not a reference
+func isFileRef(value string) bool {
+	return strings.HasPrefix(value, FileRefPrefix) ||
+		(strings.HasPrefix(value, AtFileRefPrefix) &&
+			!strings.HasPrefix(value, AtFileRefPrefix+AtFileRefPrefix) &&
+			len(value) > len(AtFileRefPrefix))
+}
+
+// readFileRef returns the file content if the value is a file reference,
+// otherwise returns the value itself
+func readFileRef(value string) (string, error) {
+	var path string
+	switch {
+	case strings.HasPrefix(value, FileRefPrefix):
+		path = value[len(FileRefPrefix):]
+	case strings.HasPrefix(value, AtFileRefPrefix+AtFileRefPrefix):
+		return value[len(AtFileRefPrefix):], nil
+	case strings.HasPrefix(value, AtFileRefPrefix) &&
+		len(value) > len(AtFileRefPrefix):
+		path = value[len(AtFileRefPrefix):]
+	default:
+		return value, nil
+	}
+
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("Can't read referenced file. %s", err.Error())
+	}
+	return strings.TrimRight(string(raw), "\r\n"), nil
+}
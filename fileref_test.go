@@ -0,0 +1,93 @@
+/*
+ * Copyright (C) 2017 eschao <esc.chao@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/eschao/config/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveFileRefs(t *testing.T) {
+	dir := t.TempDir()
+	secret := filepath.Join(dir, "secret")
+	assert := assert.New(t)
+	assert.NoError(os.WriteFile(secret, []byte("s3cret\n"), 0600))
+
+	conf := test.InterpolateConfig{
+		Home:   "file://" + secret,
+		LogDir: "@" + secret,
+		Paths:  []string{"@" + secret, "@@team", "@"},
+		Labels: map[string]string{"token": "file://" + secret},
+	}
+	assert.NoError(ResolveFileRefs(&conf))
+	assert.Equal("s3cret", conf.Home)
+	assert.Equal("s3cret", conf.LogDir)
+	assert.Equal([]string{"s3cret", "@team", "@"}, conf.Paths)
+	assert.Equal("s3cret", conf.Labels["token"])
+
+	conf = test.InterpolateConfig{Home: "@" + filepath.Join(dir, "not-exist")}
+	assert.Error(ResolveFileRefs(&conf))
+}
+
+func TestLoaderWithFileRefs(t *testing.T) {
+	dir := t.TempDir()
+	password := filepath.Join(dir, "db_password")
+	assert := assert.New(t)
+	assert.NoError(os.WriteFile(password, []byte(DB_PASSWORD+"\n"), 0600))
+
+	os.Setenv("DB_PASSWORD_FILE", password)
+	defer os.Unsetenv("DB_PASSWORD_FILE")
+
+	conf := test.RequiredConfig{}
+	loader := New(WithSources(DefaultSource, EnvSource, CliSource),
+		WithFileRefs(), WithArgs("app",
+			[]string{"-host", "localhost", "db", "-host", "@@db"}))
+	assert.NoError(loader.Load(&conf))
+	assert.Equal(DB_PASSWORD, conf.DB.Password)
+	assert.Equal("@db", conf.DB.Host)
+
+	conf = test.RequiredConfig{}
+	loader = New(WithSources(DefaultSource, CliSource), WithFileRefs(),
+		WithArgs("app", []string{"-host", "localhost", "db", "-host", "db",
+			"-password", "file://" + password}))
+	assert.NoError(loader.Load(&conf))
+	assert.Equal(DB_PASSWORD, conf.DB.Password)
+}
+
+func TestLoaderWithoutFileRefs(t *testing.T) {
+	dir := t.TempDir()
+	password := filepath.Join(dir, "db_password")
+	assert := assert.New(t)
+	assert.NoError(os.WriteFile(password, []byte(DB_PASSWORD+"\n"), 0600))
+
+	os.Setenv("DB_PASSWORD_FILE", password)
+	defer os.Unsetenv("DB_PASSWORD_FILE")
+
+	// the _FILE variables are still read
+	conf := test.RequiredConfig{}
+	loader := New(WithSources(DefaultSource, EnvSource, CliSource),
+		WithArgs("app", []string{"-host", "@daily", "-user", "@user",
+			"db", "-host", "file://" + password}))
+	assert.NoError(loader.Load(&conf))
+	assert.Equal("@daily", conf.Host)
+	assert.Equal("@user", conf.User)
+	assert.Equal("file://"+password, conf.DB.Host)
+	assert.Equal(DB_PASSWORD, conf.DB.Password)
+}
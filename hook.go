@@ -0,0 +1,92 @@
+/*
+ * Copyright (C) 2017 eschao <esc.chao@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package config
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Validator is implemented by configuration structures which validate
+// themselves, e.g: checking rules across multiple fields
+type Validator interface {
+	Validate() error
+}
+
+// AfterLoader is implemented by configuration structures which need to be
+// processed after all sources are loaded, e.g: setting derived fields
+type AfterLoader interface {
+	AfterLoad() error
+}
+
+// callAfterLoad calls AfterLoad() of the given structure value and all its
+// nested structures which implement AfterLoader
+func callAfterLoad(v reflect.Value) []error {
+	return callHooks(v, "", func(i interface{}) error {
+		if loader, ok := i.(AfterLoader); ok {
+			return loader.AfterLoad()
+		}
+		return nil
+	})
+}
+
+// callValidate calls Validate() of the given structure value and all its
+// nested structures which implement Validator
+func callValidate(v reflect.Value) []error {
+	return callHooks(v, "", func(i interface{}) error {
+		if validator, ok := i.(Validator); ok {
+			return validator.Validate()
+		}
+		return nil
+	})
+}
+
+// callHooks calls hook with the pointer of every nested structure bottom-up,
+// that means the nested structures are called before their parent. The
+// given structure value must be addressable
+func callHooks(v reflect.Value, path string,
+	hook func(interface{}) error) []error {
+	var errs []error
+	typeOfStruct := v.Type()
+	for i := 0; i < v.NumField(); i++ {
+		valueOfField := v.Field(i)
+		structOfField := typeOfStruct.Field(i)
+		if structOfField.PkgPath != "" {
+			continue
+		}
+
+		if valueOfField.Kind() == reflect.Ptr {
+			if valueOfField.IsNil() {
+				continue
+			}
+			valueOfField = valueOfField.Elem()
+		}
+
+		if valueOfField.Kind() == reflect.Struct {
+			errs = append(errs, callHooks(valueOfField,
+				joinPath(path, structOfField.Name, "."), hook)...)
+		}
+	}
+
+	if err := hook(v.Addr().Interface()); err != nil {
+		if path != "" {
+			err = fmt.Errorf("%s: %w", path, err)
+		}
+		errs = append(errs, err)
+	}
+
+	return errs
+}
@@ -0,0 +1,104 @@
+/*
+ * Copyright (C) 2017 eschao <esc.chao@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package config
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var hookCalls []string
+
+type tlsConfig struct {
+	Cert string `env:"CERT"`
+	Key  string `env:"KEY"`
+}
+
+func (this *tlsConfig) AfterLoad() error {
+	hookCalls = append(hookCalls, "tls.AfterLoad")
+	return nil
+}
+
+func (this tlsConfig) Validate() error {
+	hookCalls = append(hookCalls, "tls.Validate")
+	if (this.Cert == "") != (this.Key == "") {
+		return errors.New("cert and key must be set together")
+	}
+	return nil
+}
+
+type serverConfig struct {
+	Addr  string     `env:"ADDR" default:"localhost"`
+	URL   string     `env:"URL"`
+	TLS   tlsConfig  `env:"TLS_"`
+	Proxy *tlsConfig `env:"PROXY_"`
+}
+
+func (this *serverConfig) AfterLoad() error {
+	hookCalls = append(hookCalls, "server.AfterLoad")
+	this.URL = "https://" + this.Addr
+	return nil
+}
+
+func (this *serverConfig) Validate() error {
+	hookCalls = append(hookCalls, "server.Validate")
+	return nil
+}
+
+func TestLoaderWithHooks(t *testing.T) {
+	os.Setenv("CONFIG_TEST_HOOK_TLS_CERT", "cert.pem")
+	os.Setenv("CONFIG_TEST_HOOK_PROXY_KEY", "key.pem")
+	defer os.Unsetenv("CONFIG_TEST_HOOK_TLS_CERT")
+	defer os.Unsetenv("CONFIG_TEST_HOOK_PROXY_KEY")
+
+	assert := assert.New(t)
+	hookCalls = nil
+	conf := serverConfig{Proxy: &tlsConfig{}}
+	loader := New(WithSources(DefaultSource, EnvSource),
+		WithEnvPrefix("CONFIG_TEST_HOOK_"))
+	err := loader.Load(&conf)
+	assert.Error(err)
+	assert.Equal("https://localhost", conf.URL)
+	assert.Equal([]string{"tls.AfterLoad", "tls.AfterLoad", "server.AfterLoad",
+		"tls.Validate", "tls.Validate", "server.Validate"}, hookCalls)
+
+	var errs Errors
+	assert.True(errors.As(err, &errs))
+	assert.Equal(2, len(errs))
+	assert.Equal("TLS: cert and key must be set together", errs[0].Error())
+	assert.Equal("Proxy: cert and key must be set together", errs[1].Error())
+
+	hookCalls = nil
+	conf = serverConfig{}
+	os.Setenv("CONFIG_TEST_HOOK_TLS_KEY", "key.pem")
+	defer os.Unsetenv("CONFIG_TEST_HOOK_TLS_KEY")
+	assert.NoError(loader.Load(&conf))
+	assert.Equal([]string{"tls.AfterLoad", "server.AfterLoad", "tls.Validate",
+		"server.Validate"}, hookCalls)
+}
+
+func TestValidateWithHooks(t *testing.T) {
+	assert := assert.New(t)
+	hookCalls = nil
+	conf := serverConfig{TLS: tlsConfig{Key: "key.pem"}}
+	err := Validate(&conf)
+	assert.Error(err)
+	assert.Equal("TLS: cert and key must be set together", err.Error())
+	assert.Equal([]string{"tls.Validate", "server.Validate"}, hookCalls)
+}
@@ -0,0 +1,201 @@
+/*
+ * Copyright (C) 2017 eschao <esc.chao@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// IncludeKey is the key of JSON, Yaml and TOML configuration files to
+	// include other files, its value is a file path or a list of file paths
+	IncludeKey = "include"
+
+	// IncludeDirective is the line prefix of Properties and INI
+	// configuration files to include another file, e.g: @include db.ini
+	IncludeDirective = "@include"
+
+	// MaxIncludeDepth is the maximum depth of nested included files
+	MaxIncludeDepth = 10
+)
+
+// mergeFile merges the included files and then the given configuration file
+// into structure value. The stack is the chain of including files which is
+// used to detect cycles
+func (this *merger) mergeFile(v reflect.Value, configFile string,
+	stack []string) error {
+	configType, err := getConfigFileType(configFile)
+	if err != nil {
+		return err
+	}
+
+	raw, err := ioutil.ReadFile(configFile)
+	if err != nil {
+		return fmt.Errorf("Can't open %s config file. %s", configType,
+			err.Error())
+	}
+
+	includes, err := getIncludes(raw, configType)
+	if err != nil {
+		return fmt.Errorf("%s: %s", configFile, err.Error())
+	}
+
+	includes, stack, err = resolveIncludes(configFile, includes, stack)
+	if err != nil {
+		return err
+	}
+
+	for _, include := range includes {
+		if err := this.mergeFile(v, include, stack); err != nil {
+			return err
+		}
+	}
+
+	if this.strict {
+		if err := checkUnknownKeys(v.Type(), configFile, raw,
+			configType); err != nil {
+			return err
+		}
+	}
+
+	if err := mergeBytes(v, raw, configType); err != nil {
+		return fmt.Errorf("%s: %s", configFile, err.Error())
+	}
+
+	if this.origins != nil {
+		fileOrigins(v, configFile, raw, configType, this.origins)
+	}
+	return nil
+}
+
+// resolveIncludes resolves the files included by a configuration file
+// relative to it, and returns them with the stack of including files. An
+// error is returned if the file is already in the stack or the stack is too
+// deep
+func resolveIncludes(configFile string, includes []string,
+	stack []string) ([]string, []string, error) {
+	if len(includes) == 0 {
+		return nil, stack, nil
+	}
+
+	abs, err := filepath.Abs(configFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, file := range stack {
+		if file == abs {
+			return nil, nil, fmt.Errorf("Include cycle detected: %s -> %s",
+				strings.Join(stack, " -> "), abs)
+		}
+	}
+
+	if len(stack) >= MaxIncludeDepth {
+		return nil, nil, fmt.Errorf("Include depth exceeds %d: %s",
+			MaxIncludeDepth, abs)
+	}
+
+	files := make([]string, len(includes))
+	for i, include := range includes {
+		if !filepath.IsAbs(include) {
+			include = filepath.Join(filepath.Dir(abs), include)
+		}
+		files[i] = include
+	}
+	return files, append(stack, abs), nil
+}
+
+// getIncludes returns the files included by configuration data
+func getIncludes(data []byte, configType string) ([]string, error) {
+	var value interface{}
+	switch configType {
+	case JSONConfigType:
+		var tree map[string]interface{}
+		if err := json.Unmarshal(data, &tree); err != nil {
+			return nil, err
+		}
+		value = tree[IncludeKey]
+	case YamlConfigType:
+		var tree map[string]interface{}
+		if err := yaml.Unmarshal(data, &tree); err != nil {
+			return nil, err
+		}
+		value = tree[IncludeKey]
+	case TomlConfigType:
+		var tree map[string]interface{}
+		if _, err := toml.Decode(string(data), &tree); err != nil {
+			return nil, err
+		}
+		value = tree[IncludeKey]
+	case PropConfigType, IniConfigType:
+		return getIncludeDirectives(data)
+	}
+
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{v}, nil
+	case []interface{}:
+		includes := make([]string, 0, len(v))
+		for _, e := range v {
+			include, ok := e.(string)
+			if !ok {
+				return nil, fmt.Errorf("Invalid %s: %v", IncludeKey, value)
+			}
+			includes = append(includes, include)
+		}
+		return includes, nil
+	}
+
+	return nil, fmt.Errorf("Invalid %s: %v", IncludeKey, value)
+}
+
+// getIncludeDirectives returns the files included by @include lines
+func getIncludeDirectives(data []byte) ([]string, error) {
+	var includes []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		if include, ok := includeDirective(scanner.Text()); ok {
+			includes = append(includes, include)
+		}
+	}
+	return includes, scanner.Err()
+}
+
+// includeDirective returns the included file if the line is an @include one
+func includeDirective(line string) (string, bool) {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, IncludeDirective) {
+		return "", false
+	}
+
+	include := line[len(IncludeDirective):]
+	if include == "" || (include[0] != ' ' && include[0] != '\t') {
+		return "", false
+	}
+	return strings.TrimSpace(include), true
+}
@@ -0,0 +1,96 @@
+/*
+ * Copyright (C) 2017 eschao <esc.chao@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/eschao/config/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIncludeConfigFile(t *testing.T) {
+	_, curTestFile, _, _ := runtime.Caller(0)
+	path := filepath.Dir(curTestFile) + "/test/include"
+
+	assert := assert.New(t)
+	conf := test.MergeConfig{}
+	assert.NoError(ParseConfigFile(&conf, path+"/service.json"))
+	assert.Equal("service", conf.Name)
+	assert.Equal("shared-db-host", conf.DB.Host)
+	assert.Equal(6543, conf.DB.Port)
+	assert.Equal("/var/log/db", conf.DB.Log.Path)
+	assert.Equal("debug", conf.DB.Log.Level)
+}
+
+func TestIncludeDirective(t *testing.T) {
+	_, curTestFile, _, _ := runtime.Caller(0)
+	path := filepath.Dir(curTestFile) + "/test/include"
+
+	assert := assert.New(t)
+	conf := test.DBConfig{}
+	assert.NoError(ParseConfigFile(&conf, path+"/db.properties"))
+	assert.Equal("base-db-host", conf.Host)
+	assert.Equal(4321, conf.Port)
+
+	conf = test.DBConfig{}
+	assert.NoError(ParseConfigFile(&conf, path+"/db.ini"))
+	assert.Equal("base-db-host", conf.Host)
+	assert.Equal(4321, conf.Port)
+	assert.Equal("error", conf.Log.Level)
+}
+
+func TestIncludeErrors(t *testing.T) {
+	_, curTestFile, _, _ := runtime.Caller(0)
+	path := filepath.Dir(curTestFile) + "/test/include"
+
+	assert := assert.New(t)
+	conf := test.MergeConfig{}
+	err := ParseConfigFile(&conf, path+"/cycle-a.yaml")
+	assert.Error(err)
+	assert.True(strings.HasPrefix(err.Error(), "Include cycle detected"))
+
+	dir := t.TempDir()
+	for i := 0; i <= MaxIncludeDepth; i++ {
+		content := fmt.Sprintf("include: %d.yaml\n", i+1)
+		assert.NoError(os.WriteFile(filepath.Join(dir,
+			fmt.Sprintf("%d.yaml", i)), []byte(content), 0644))
+	}
+	err = ParseConfigFile(&conf, filepath.Join(dir, "0.yaml"))
+	assert.Error(err)
+	assert.True(strings.HasPrefix(err.Error(), "Include depth exceeds"))
+
+	invalid := filepath.Join(dir, "invalid.json")
+	assert.NoError(os.WriteFile(invalid, []byte(`{"include": 1}`), 0644))
+	assert.Error(ParseConfigFile(&conf, invalid))
+}
+
+func TestIncludeDirectiveLine(t *testing.T) {
+	assert := assert.New(t)
+	include, ok := includeDirective("  @include  common.ini ")
+	assert.True(ok)
+	assert.Equal("common.ini", include)
+
+	_, ok = includeDirective("@included = true")
+	assert.False(ok)
+	_, ok = includeDirective("@include")
+	assert.False(ok)
+}
@@ -0,0 +1,162 @@
+/*
+ * Copyright (C) 2017 eschao <esc.chao@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/eschao/config/utils"
+)
+
+// readIni reads INI content from the given reader and returns values keyed by
+// the section and key joined by '.', e.g: [database.log] path=/var/log is
+// returned as database.log.path. A key could be repeated to have multiple
+// values. It supports:
+//   - comment lines beginning with ';' or '#'
+//   - '=' or ':' as key/value separator
+//   - values quoted by double or single quotes
+//   - @include lines which are skipped
+func readIni(r io.Reader) (map[string][]string, error) {
+	values := make(map[string][]string)
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	section := ""
+
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == ';' || line[0] == '#' {
+			continue
+		}
+
+		if _, ok := includeDirective(line); ok {
+			continue
+		}
+
+		if line[0] == '[' {
+			if line[len(line)-1] != ']' {
+				return nil, fmt.Errorf("line %d: invalid section: %s", lineNo,
+					line)
+			}
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+
+		index := strings.IndexAny(line, "=:")
+		if index <= 0 {
+			return nil, fmt.Errorf("line %d: invalid key value: %s", lineNo,
+				line)
+		}
+
+		key := strings.TrimSpace(line[:index])
+		value := strings.TrimSpace(line[index+1:])
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') &&
+			value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+
+		key = joinPath(section, key, ".")
+		values[key] = append(values[key], value)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
+
+// setIniValue sets structure with INI values. The ini tag of a nested
+// structure is the section name of its fields, e.g: [log] for Log field with
+// ini:"log" tag, and [log.file] for the nested structure with ini:"file" tag
+// in Log
+func setIniValue(v reflect.Value, values map[string][]string,
+	prefix string) error {
+	typeOfStruct := v.Type()
+	for i := 0; i < v.NumField(); i++ {
+		valueOfField := v.Field(i)
+		kindOfField := valueOfField.Kind()
+		structOfField := typeOfStruct.Field(i)
+		iniName := structOfField.Tag.Get("ini")
+
+		subPrefix := prefix
+		if iniName != "" {
+			subPrefix = prefix + iniName + "."
+		}
+
+		if kindOfField == reflect.Ptr {
+			if valueOfField.IsNil() || !valueOfField.CanSet() ||
+				valueOfField.Elem().Kind() != reflect.Struct {
+				continue
+			}
+			if err := setIniValue(valueOfField.Elem(), values,
+				subPrefix); err != nil {
+				return err
+			}
+			continue
+		} else if kindOfField == reflect.Struct {
+			if err := setIniValue(valueOfField, values, subPrefix); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if iniName == "" {
+			continue
+		}
+
+		iniValues, ok := values[prefix+iniName]
+		if !ok {
+			continue
+		}
+
+		if !valueOfField.CanSet() {
+			return fmt.Errorf("%s: can't be set", structOfField.Name)
+		}
+
+		var err error
+		if kindOfField == reflect.Slice && len(iniValues) > 1 {
+			// repeated keys are the elements of slice
+			slice := reflect.MakeSlice(valueOfField.Type(), len(iniValues),
+				len(iniValues))
+			for i, iniValue := range iniValues {
+				if err = utils.SetValue(slice.Index(i), iniValue, ""); err != nil {
+					break
+				}
+			}
+			if err == nil {
+				valueOfField.Set(slice)
+			}
+		} else {
+			sp, ok := structOfField.Tag.Lookup("separator")
+			if !ok {
+				sp = ":"
+			}
+			// the last one wins if a key of non-slice field is repeated
+			err = utils.SetValue(valueOfField, iniValues[len(iniValues)-1], sp)
+		}
+
+		if err != nil {
+			return fmt.Errorf("%s: %s", prefix+iniName, err.Error())
+		}
+	}
+
+	return nil
+}
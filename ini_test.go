@@ -0,0 +1,82 @@
+/*
+ * Copyright (C) 2017 eschao <esc.chao@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadIni(t *testing.T) {
+	data := `
+global = value
+; comment
+# comment
+[server]
+host: localhost
+name = "quoted value"
+path = /a
+path = /b
+
+[ server.tls ]
+cert = cert.pem
+`
+	assert := assert.New(t)
+	values, err := readIni(strings.NewReader(data))
+	assert.NoError(err)
+	assert.Equal(map[string][]string{
+		"global":          {"value"},
+		"server.host":     {"localhost"},
+		"server.name":     {"quoted value"},
+		"server.path":     {"/a", "/b"},
+		"server.tls.cert": {"cert.pem"},
+	}, values)
+
+	_, err = readIni(strings.NewReader("[server"))
+	assert.Error(err)
+	_, err = readIni(strings.NewReader("[server]\nhost"))
+	assert.Error(err)
+}
+
+func TestSetIniValue(t *testing.T) {
+	type iniConfig struct {
+		Paths  []string `ini:"path"`
+		Values []int    `ini:"values" separator:","`
+		Ports  []uint16 `ini:"port"`
+		Name   string   `ini:"name"`
+	}
+
+	values := map[string][]string{
+		"path":   {"/a", "/b:/c"},
+		"values": {"1,2,3"},
+		"port":   {"80", "443"},
+		"name":   {"first", "second"},
+	}
+
+	assert := assert.New(t)
+	conf := iniConfig{}
+	assert.NoError(setIniValue(reflect.ValueOf(&conf).Elem(), values, ""))
+	assert.Equal([]string{"/a", "/b:/c"}, conf.Paths)
+	assert.Equal([]int{1, 2, 3}, conf.Values)
+	assert.Equal([]uint16{80, 443}, conf.Ports)
+	assert.Equal("second", conf.Name)
+
+	values["port"] = []string{"80", "xxx"}
+	assert.Error(setIniValue(reflect.ValueOf(&conf).Elem(), values, ""))
+}
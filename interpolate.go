@@ -0,0 +1,253 @@
+/*
+ * Copyright (C) 2017 eschao <esc.chao@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// refTags are the tags whose configuration names could be used to reference
+// a field, besides its Go path
+var refTags = []string{"json", "yaml", "toml", "prop", "ini"}
+
+// interpolator resolves references in string values of a structure
+type interpolator struct {
+	refs      map[string]*field // fields keyed by Go path and config names
+	resolved  map[string]bool   // Go paths of resolved fields
+	resolving []string          // Go paths of fields being resolved
+	skip      func(*field) bool // checks if the value of field is kept as is
+}
+
+// Interpolate resolves references in the string values of given structure
+// interface, including the elements of string slices and the values of
+// string maps. A reference could be:
+//   - ${VAR}: environment variable, it is empty if not set
+//   - ${VAR:-fallback}: environment variable with fallback if it is not set
+//     or empty, the fallback could contain references as well
+//   - ${path.to.field}: other field referenced by Go path, e.g: ${Log.Path},
+//     or by name in JSON, Yaml, TOML, Properties or INI, e.g: ${log.path}
+//
+// A field reference has higher priority than environment variable, and $${
+// is escaped as ${, e.g: $${user.name} is kept as ${user.name}. The values of
+// secret fields are kept as is, but they could be referenced by others. An
+// unknown field reference or reference cycle fails
+func Interpolate(i interface{}) error {
+	valueOfStruct, err := structValueOf(i)
+	if err != nil {
+		return err
+	}
+	return interpolate(valueOfStruct, nil)
+}
+
+// interpolate resolves references in the string values of structure value,
+// the values of secret fields and the fields checked by skip are kept as is
+func interpolate(v reflect.Value, skip func(*field) bool) error {
+	this := &interpolator{
+		refs:     make(map[string]*field),
+		resolved: make(map[string]bool),
+		skip: func(f *field) bool {
+			return isSecret(f.field) || (skip != nil && skip(f))
+		},
+	}
+
+	var fields []*field
+	walkFields(v, "", func(f *field) error {
+		this.refs[f.path] = f
+		for _, tag := range refTags {
+			if name, ok := f.names[tag]; ok {
+				if _, exists := this.refs[name]; !exists {
+					this.refs[name] = f
+				}
+			}
+		}
+		fields = append(fields, f)
+		return nil
+	})
+
+	for _, f := range fields {
+		if err := this.resolveField(f); err != nil {
+			return fmt.Errorf("%s: %s", f.path, err.Error())
+		}
+	}
+	return nil
+}
+
+// resolveField resolves references in the value of given field
+func (this *interpolator) resolveField(f *field) error {
+	if this.resolved[f.path] {
+		return nil
+	}
+
+	if this.skip(f) {
+		this.resolved[f.path] = true
+		return nil
+	}
+
+	for _, path := range this.resolving {
+		if path == f.path {
+			return fmt.Errorf("Reference cycle detected: %s -> %s",
+				strings.Join(this.resolving, " -> "), f.path)
+		}
+	}
+
+	this.resolving = append(this.resolving, f.path)
+	defer func() {
+		this.resolving = this.resolving[:len(this.resolving)-1]
+	}()
+
+	v := f.value
+	switch {
+	case v.Kind() == reflect.String:
+		s, err := this.expand(v.String())
+		if err != nil {
+			return err
+		}
+		v.SetString(s)
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.String:
+		for i := 0; i < v.Len(); i++ {
+			s, err := this.expand(v.Index(i).String())
+			if err != nil {
+				return err
+			}
+			v.Index(i).SetString(s)
+		}
+	case v.Kind() == reflect.Map && v.Type().Elem().Kind() == reflect.String:
+		for _, key := range v.MapKeys() {
+			s, err := this.expand(v.MapIndex(key).String())
+			if err != nil {
+				return err
+			}
+			v.SetMapIndex(key, reflect.ValueOf(s).Convert(v.Type().Elem()))
+		}
+	}
+
+	this.resolved[f.path] = true
+	return nil
+}
+
+// expand replaces references in the string with their values
+func (this *interpolator) expand(s string) (string, error) {
+	if !strings.Contains(s, "${") {
+		return s, nil
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '$' || i+1 >= len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+
+		if strings.HasPrefix(s[i+1:], "${") {
+			b.WriteString("${")
+			i += 2
+			continue
+		}
+
+		if s[i+1] != '{' {
+			b.WriteByte(s[i])
+			continue
+		}
+
+		end := closingBrace(s, i+2)
+		if end < 0 {
+			return "", fmt.Errorf("Unclosed reference: %s", s[i:])
+		}
+
+		value, err := this.lookup(s[i+2 : end])
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(value)
+		i = end
+	}
+
+	return b.String(), nil
+}
+
+// lookup returns the value of a reference expression: NAME or NAME:-fallback
+func (this *interpolator) lookup(expr string) (string, error) {
+	name, fallback := expr, ""
+	hasFallback := false
+	if index := strings.Index(expr, ":-"); index >= 0 {
+		name, fallback = expr[:index], expr[index+2:]
+		hasFallback = true
+	}
+	name = strings.TrimSpace(name)
+
+	value := ""
+	if f, ok := this.refs[name]; ok {
+		if err := this.resolveField(f); err != nil {
+			return "", err
+		}
+
+		var err error
+		if value, err = fieldString(f.value); err != nil {
+			return "", fmt.Errorf("Can't reference ${%s}. %s", name,
+				err.Error())
+		}
+	} else if strings.Contains(name, ".") {
+		if !hasFallback {
+			return "", fmt.Errorf("Unknown reference: ${%s}", name)
+		}
+	} else {
+		value = os.Getenv(name)
+	}
+
+	if value == "" && hasFallback {
+		return this.expand(fallback)
+	}
+	return value, nil
+}
+
+// fieldString returns the string of a field value to be referenced
+func fieldString(v reflect.Value) (string, error) {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return "", nil
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Struct, reflect.Map:
+		return "", fmt.Errorf("%s is not a value", v.Type().String())
+	}
+	return fmt.Sprint(v.Interface()), nil
+}
+
+// closingBrace returns index of the '}' which closes a reference started at
+// the given index, nested braces are skipped. It returns -1 if not found
+func closingBrace(s string, start int) int {
+	depth := 1
+	for i := start; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
@@ -0,0 +1,132 @@
+/*
+ * Copyright (C) 2017 eschao <esc.chao@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/eschao/config/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInterpolate(t *testing.T) {
+	os.Setenv("CONFIG_TEST_HOME", "/home/env")
+	defer os.Unsetenv("CONFIG_TEST_HOME")
+
+	assert := assert.New(t)
+	conf := test.InterpolateConfig{
+		Home:    "${CONFIG_TEST_HOME}",
+		LogDir:  "${Home}/logs",
+		Paths:   []string{"${logDir}/a", "$${logDir}/b"},
+		Labels:  map[string]string{"dir": "${log.path:-${logDir}}"},
+		Port:    9090,
+		Address: "localhost:${port}",
+		Token:   "${token",
+		Log: test.LogConfig{
+			Path:  "${LogDir}/service.log",
+			Level: "${CONFIG_TEST_NOT_EXIST:-debug}",
+		},
+	}
+	assert.NoError(Interpolate(&conf))
+	assert.Equal("${token", conf.Token)
+	assert.Equal("/home/env", conf.Home)
+	assert.Equal("/home/env/logs", conf.LogDir)
+	assert.Equal([]string{"/home/env/logs/a", "${logDir}/b"}, conf.Paths)
+	assert.Equal("/home/env/logs/service.log", conf.Labels["dir"])
+	assert.Equal("localhost:9090", conf.Address)
+	assert.Equal("/home/env/logs/service.log", conf.Log.Path)
+	assert.Equal("debug", conf.Log.Level)
+}
+
+func TestInterpolateErrors(t *testing.T) {
+	assert := assert.New(t)
+	conf := test.InterpolateConfig{
+		Home:   "${logDir}",
+		LogDir: "${Log.Path}",
+		Log:    test.LogConfig{Path: "${home}"},
+	}
+	err := Interpolate(&conf)
+	assert.Error(err)
+	assert.True(strings.Contains(err.Error(),
+		"Reference cycle detected: Home -> LogDir -> Log.Path -> Home"))
+
+	conf = test.InterpolateConfig{Home: "${not.exist}"}
+	assert.Error(Interpolate(&conf))
+
+	conf = test.InterpolateConfig{Home: "${log}"}
+	assert.Error(Interpolate(&conf))
+
+	conf = test.InterpolateConfig{Home: "${HOME"}
+	assert.Error(Interpolate(&conf))
+}
+
+func TestLoaderWithInterpolation(t *testing.T) {
+	os.Unsetenv("CONFIG_TEST_HOME")
+	os.Setenv("CONFIG_TEST_INTERPOLATE_LOG_DIR", "${home}/env-logs")
+	defer os.Unsetenv("CONFIG_TEST_INTERPOLATE_LOG_DIR")
+
+	assert := assert.New(t)
+	conf := test.InterpolateConfig{}
+	loader := New(WithSources(DefaultSource, EnvSource))
+	assert.NoError(loader.Load(&conf))
+	assert.Equal("/home/test", conf.Home)
+	assert.Equal("/home/test/env-logs", conf.LogDir)
+	assert.Equal("localhost:8080", conf.Address)
+
+	// $${ is escaped
+	os.Setenv("CONFIG_TEST_INTERPOLATE_LOG_DIR", "Hello $${user.name}")
+	assert.NoError(loader.Load(&conf))
+	assert.Equal("Hello ${user.name}", conf.LogDir)
+
+	// secret values and the values read from files are kept
+	file := filepath.Join(t.TempDir(), "log_dir")
+	assert.NoError(os.WriteFile(file, []byte("${user.name}"), 0644))
+	os.Unsetenv("CONFIG_TEST_INTERPOLATE_LOG_DIR")
+	os.Setenv("CONFIG_TEST_INTERPOLATE_LOG_DIR_FILE", file)
+	os.Setenv("CONFIG_TEST_INTERPOLATE_TOKEN", "${token}")
+	defer os.Unsetenv("CONFIG_TEST_INTERPOLATE_LOG_DIR_FILE")
+	defer os.Unsetenv("CONFIG_TEST_INTERPOLATE_TOKEN")
+	conf = test.InterpolateConfig{}
+	assert.NoError(loader.Load(&conf))
+	assert.Equal("${user.name}", conf.LogDir)
+	assert.Equal("${token}", conf.Token)
+
+	os.Setenv("CONFIG_TEST_INTERPOLATE_HOME", "@"+file)
+	defer os.Unsetenv("CONFIG_TEST_INTERPOLATE_HOME")
+	conf = test.InterpolateConfig{}
+	assert.NoError(New(WithSources(DefaultSource, EnvSource),
+		WithFileRefs()).Load(&conf))
+	assert.Equal("${user.name}", conf.Home)
+}
+
+func TestLoaderWithoutInterpolation(t *testing.T) {
+	os.Setenv("CONFIG_TEST_INTERPOLATE_LOG_DIR", "Hello ${user.name}")
+	defer os.Unsetenv("CONFIG_TEST_INTERPOLATE_LOG_DIR")
+
+	assert := assert.New(t)
+	conf := test.InterpolateConfig{}
+	assert.Error(New(WithSources(EnvSource)).Load(&conf))
+
+	conf = test.InterpolateConfig{}
+	loader := New(WithSources(DefaultSource, EnvSource),
+		WithoutInterpolation())
+	assert.NoError(loader.Load(&conf))
+	assert.Equal("Hello ${user.name}", conf.LogDir)
+	assert.Equal("localhost:${port}", conf.Address)
+}
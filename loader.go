@@ -0,0 +1,565 @@
+/*
+ * Copyright (C) 2017 eschao <esc.chao@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/eschao/config/cli"
+	"github.com/eschao/config/env"
+)
+
+// Source defines a kind of configuration source
+type Source string
+
+const (
+	DefaultSource Source = "default"
+	FileSource    Source = "file"
+	EnvSource     Source = "env"
+	CliSource     Source = "cli"
+	DirSource     Source = "dir"
+)
+
+// DefaultSources is the default loading order of configuration sources, the
+// latter source has higher priority than the former one
+var DefaultSources = []Source{DefaultSource, FileSource, EnvSource, CliSource}
+
+// SourceError is an error reported by a configuration source
+type SourceError struct {
+	Source Source
+	Err    error
+}
+
+func (this *SourceError) Error() string {
+	return fmt.Sprintf("%s: %s", this.Source, this.Err.Error())
+}
+
+func (this *SourceError) Unwrap() error {
+	return this.Err
+}
+
+// Errors is a list of errors aggregated from all configuration sources
+type Errors []error
+
+func (this Errors) Error() string {
+	msgs := make([]string, len(this))
+	for i, err := range this {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+func (this Errors) Unwrap() []error {
+	return this
+}
+
+// Option defines a function to customize Loader
+type Option func(*Loader)
+
+// Loader loads configurations from multiple sources in a defined order.
+// Every source only sets the fields it provides, so a field keeps the value
+// from a former source if a latter one doesn't provide it
+type Loader struct {
+	sources       []Source
+	name          string
+	args          []string
+	configFiles   []string
+	configNames   []string
+	searchPaths   []string
+	app           string
+	configFlag    string
+	profile       string
+	profileFlag   string
+	dir           string
+	dirTag        string
+	interval      time.Duration
+	onChange      []func(old, new interface{})
+	onError       []func(err error)
+	onReload      []func(err error)
+	debounce      time.Duration
+	envPrefix     string
+	strict        bool
+	fileRefs      bool
+	noInterpolate bool
+	mutex         sync.Mutex
+	loaded        interface{}       // structure pointer of the last load
+	origins       map[string]Origin // origins of the last load
+}
+
+// New creates a Loader with given options. Without any option, the Loader
+// loads default values, default config file, environment variables and
+// command line in order
+func New(opts ...Option) *Loader {
+	loader := Loader{
+		sources:  DefaultSources,
+		name:     os.Args[0],
+		args:     os.Args[1:],
+		interval: DefaultWatchInterval,
+		debounce: DefaultReloadDebounce,
+	}
+
+	for _, opt := range opts {
+		opt(&loader)
+	}
+
+	return &loader
+}
+
+// WithSources sets sources and their loading order. The latter source has
+// higher priority than the former one
+func WithSources(sources ...Source) Option {
+	return func(loader *Loader) {
+		loader.sources = sources
+	}
+}
+
+// WithConfigFile sets configuration file. If it is not set, the default
+// config file will be searched and the file source is skipped if not found
+func WithConfigFile(configFile string) Option {
+	return func(loader *Loader) {
+		loader.configFiles = []string{configFile}
+	}
+}
+
+// WithConfigFiles sets multiple configuration files which are deep-merged in
+// order, see ParseConfigFiles
+func WithConfigFiles(configFiles ...string) Option {
+	return func(loader *Loader) {
+		loader.configFiles = configFiles
+	}
+}
+
+// WithConfigNames sets the base names of configuration files to search if
+// no config file is set, the default is "config"
+func WithConfigNames(names ...string) Option {
+	return func(loader *Loader) {
+		loader.configNames = names
+	}
+}
+
+// WithSearchPaths sets the folders to search configuration files if no config
+// file is set, the default are DefaultSearchPaths of the application
+func WithSearchPaths(paths ...string) Option {
+	return func(loader *Loader) {
+		loader.searchPaths = paths
+	}
+}
+
+// WithAppName sets the application name for DefaultSearchPaths, the default
+// is the executable file name
+func WithAppName(app string) Option {
+	return func(loader *Loader) {
+		loader.app = app
+	}
+}
+
+// WithConfigFlag sets a command line flag to tell where to locate config
+// file. It has higher priority than the file set by WithConfigFile
+func WithConfigFlag(configFlag string) Option {
+	return func(loader *Loader) {
+		loader.configFlag = configFlag
+	}
+}
+
+// WithProfile sets the active profile, the profile configuration file
+// config.<profile>.<ext> is merged on top of every configuration file. It
+// has lower priority than CONFIG_PROFILE environment variable and the
+// command line flag set by WithProfileFlag
+func WithProfile(profile string) Option {
+	return func(loader *Loader) {
+		loader.profile = profile
+	}
+}
+
+// WithProfileFlag sets a command line flag to tell the active profile
+func WithProfileFlag(profileFlag string) Option {
+	return func(loader *Loader) {
+		loader.profileFlag = profileFlag
+	}
+}
+
+// WithDir sets the configuration directory of DirSource, see ParseDirWith
+func WithDir(dir string) Option {
+	return func(loader *Loader) {
+		loader.dir = dir
+	}
+}
+
+// WithDirTag sets the tag to define file names in the configuration
+// directory, the default is dir tag. With env tag, the file names are the
+// environment variable names with the prefix set by WithEnvPrefix
+func WithDirTag(tag string) Option {
+	return func(loader *Loader) {
+		loader.dirTag = tag
+	}
+}
+
+// WithEnvPrefix sets the prefix of environment variables
+func WithEnvPrefix(prefix string) Option {
+	return func(loader *Loader) {
+		loader.envPrefix = prefix
+	}
+}
+
+// WithStrict makes unknown keys in configuration files and unknown
+// environment variables errors, see ParseConfigFileStrict and
+// env.ParseStrict
+func WithStrict() Option {
+	return func(loader *Loader) {
+		loader.strict = true
+	}
+}
+
+// WithFileRefs reads the string values referring to files after loading,
+// see ResolveFileRefs. The _FILE variants of environment variables are
+// always read
+func WithFileRefs() Option {
+	return func(loader *Loader) {
+		loader.fileRefs = true
+	}
+}
+
+// WithoutInterpolation keeps references in string values as they are after
+// loading, see Interpolate
+func WithoutInterpolation() Option {
+	return func(loader *Loader) {
+		loader.noInterpolate = true
+	}
+}
+
+// WithArgs sets command name and arguments for parsing command line, the
+// default are os.Args[0] and os.Args[1:]
+func WithArgs(name string, args []string) Option {
+	return func(loader *Loader) {
+		loader.name = name
+		loader.args = args
+	}
+}
+
+// Load loads all sources in order into given structure pointer. After that,
+// it resolves references in string values unless WithoutInterpolation is
+// set, see Interpolate, reads the
+// values referring to files if WithFileRefs is set, calls
+// AfterLoad() of structures implementing AfterLoader, checks required
+// fields, validates fields with tags and calls Validate() of structures
+// implementing Validator. The hooks are called from the nested structures to
+// the given one. All errors are returned together as Errors
+func (this *Loader) Load(i interface{}) error {
+	_, err := this.load(i)
+	return err
+}
+
+// load loads all sources into given structure pointer, and returns the
+// origins of fields keyed by their Go path. The origins are nil if nothing is
+// loaded
+func (this *Loader) load(i interface{}) (map[string]Origin, error) {
+	ptrRef := reflect.ValueOf(i)
+	if ptrRef.Kind() != reflect.Ptr || ptrRef.IsNil() ||
+		ptrRef.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("Expect a structure pointer type instead of %s",
+			ptrRef.Kind().String())
+	}
+
+	configFiles, args, err := this.resolveConfigFiles(ptrRef.Elem().Type())
+	if err != nil {
+		return nil, Errors{&SourceError{Source: FileSource, Err: err}}
+	}
+
+	var errs Errors
+	origins := make(map[string]Origin)
+	for _, source := range this.sources {
+		var err error
+		switch source {
+		case DefaultSource:
+			err = ParseDefault(i)
+			defaultOrigins(ptrRef.Elem(), origins)
+		case FileSource:
+			err = this.loadFile(i, configFiles, origins)
+		case EnvSource:
+			if this.strict {
+				err = env.ParseStrict(i, this.envPrefix)
+			} else {
+				err = env.ParseWith(i, this.envPrefix)
+			}
+			envOrigins(ptrRef.Elem(), this.envPrefix, origins)
+		case CliSource:
+			var flags map[string]bool
+			flags, err = this.loadCli(i, args)
+			cliOrigins(ptrRef.Elem(), flags, origins)
+		case DirSource:
+			err = this.loadDir(i, origins)
+		default:
+			err = fmt.Errorf("Can't support source: %s", source)
+		}
+
+		if err != nil {
+			errs = append(errs, &SourceError{Source: source, Err: err})
+		}
+	}
+
+	if !this.noInterpolate {
+		if err := interpolate(ptrRef.Elem(), func(f *field) bool {
+			return this.keepsValue(f, origins)
+		}); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if this.fileRefs {
+		if err := resolveFileRefs(ptrRef.Elem()); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	errs = append(errs, callAfterLoad(ptrRef.Elem())...)
+	if err := checkRequired(ptrRef.Elem(), this.envPrefix,
+		origins); err != nil {
+		errs = append(errs, err)
+	}
+
+	if err := validateFields(ptrRef.Elem(), origins); err != nil {
+		errs = append(errs, err)
+	}
+	errs = append(errs, callValidate(ptrRef.Elem())...)
+	this.record(i, origins)
+
+	if len(errs) > 0 {
+		return origins, errs
+	}
+	return origins, nil
+}
+
+// resolveConfigFiles returns the config files to load and the command line
+// arguments without config and profile flags. The config files are the given
+// ones or the default one if it exists, and every file is followed by its
+// profile config file if any. The flags are looked up in the arguments of
+// the top command of structure type
+func (this *Loader) resolveConfigFiles(t reflect.Type) ([]string, []string,
+	error) {
+	configFiles, args := this.configFiles, this.args
+	var flags *flag.FlagSet
+	if this.configFlag != "" || this.profileFlag != "" {
+		flags = this.commandFlags(t)
+	}
+
+	if this.configFlag != "" {
+		var file string
+		file, args = extractFlag(args, this.configFlag, flags)
+		if file != "" {
+			configFiles = []string{file}
+		}
+	}
+
+	profile := this.profile
+	if value, ok := os.LookupEnv(ProfileEnv); ok && value != "" {
+		profile = value
+	}
+	if this.profileFlag != "" {
+		var value string
+		value, args = extractFlag(args, this.profileFlag, flags)
+		if value != "" {
+			profile = value
+		}
+	}
+
+	if !this.hasSource(FileSource) {
+		return nil, args, nil
+	}
+
+	if len(configFiles) == 0 {
+		file, err := this.findConfigFile()
+		if err != nil {
+			if _, ok := err.(*NotFoundError); ok {
+				return nil, args, nil
+			}
+			return nil, args, err
+		}
+		configFiles = []string{file}
+	}
+
+	var files []string
+	for _, configFile := range configFiles {
+		files = append(files, configFile)
+		if profileFile, ok := getProfileConfigFile(configFile,
+			profile); ok {
+			files = append(files, profileFile)
+		}
+	}
+
+	return files, args, nil
+}
+
+// hasSource checks if the given source is loaded
+func (this *Loader) hasSource(source Source) bool {
+	for _, s := range this.sources {
+		if s == source {
+			return true
+		}
+	}
+	return false
+}
+
+// loadFile merges the config files and records the origins of merged fields,
+// nothing is loaded if no file is given
+func (this *Loader) loadFile(i interface{}, configFiles []string,
+	origins map[string]Origin) error {
+	if len(configFiles) == 0 {
+		return nil
+	}
+
+	valueOfStruct, err := structValueOf(i)
+	if err != nil {
+		return err
+	}
+	merger := &merger{origins: origins, strict: this.strict}
+	return merger.mergeFiles(valueOfStruct, configFiles)
+}
+
+// findConfigFile searches the default config file with the configured base
+// names and search paths
+func (this *Loader) findConfigFile() (string, error) {
+	names := this.configNames
+	if len(names) == 0 {
+		names = DefaultConfigNames
+	}
+
+	paths := this.searchPaths
+	if len(paths) == 0 {
+		app := this.app
+		if app == "" {
+			app = appName()
+		}
+		paths = DefaultSearchPaths(app)
+	}
+
+	return FindConfigFile(names, paths)
+}
+
+// keepsValue checks if the value of field is kept as is by interpolation: it
+// is read from the file of a _FILE environment variable, or it refers to a
+// file if WithFileRefs is set
+func (this *Loader) keepsValue(f *field, origins map[string]Origin) bool {
+	origin := lookupOrigin(origins, f.path)
+	if origin.Source == EnvSource && origin.File != "" {
+		return true
+	}
+	return this.fileRefs && hasFileRef(f.value)
+}
+
+// loadDir parses the configuration directory and records the origins of
+// fields read from files
+func (this *Loader) loadDir(i interface{}, origins map[string]Origin) error {
+	if this.dir == "" {
+		return fmt.Errorf("No config directory is set")
+	}
+
+	tag, prefix := this.dirTag, ""
+	if tag == "" {
+		tag = DefaultDirTag
+	} else if tag == "env" {
+		prefix = this.envPrefix
+	}
+	return parseDir(i, this.dir, prefix, tag, origins)
+}
+
+// loadCli parses command line arguments and returns the flags set in them,
+// named like cli names of fields, e.g: log -path. A bad flag is returned as
+// an error instead of exiting
+func (this *Loader) loadCli(i interface{}, args []string) (map[string]bool,
+	error) {
+	cmd := cli.NewWithErrorHandling(this.name, flag.ContinueOnError)
+	if err := cmd.Init(i); err != nil {
+		return nil, err
+	}
+
+	err := cmd.Parse(args)
+	flags := make(map[string]bool)
+	setFlags(cmd, "", flags)
+	return flags, err
+}
+
+// setFlags records the flags set in the command and its sub-commands
+func setFlags(cmd *cli.Command, prefix string, flags map[string]bool) {
+	cmd.FlagSet.Visit(func(f *flag.Flag) {
+		flags[joinPath(prefix, "-"+f.Name, " ")] = true
+	})
+	for name, subCmd := range cmd.SubCommands {
+		setFlags(subCmd, joinPath(prefix, name, " "), flags)
+	}
+}
+
+// commandFlags returns the flags of the top command of structure type
+func (this *Loader) commandFlags(t reflect.Type) *flag.FlagSet {
+	cmd := cli.NewWithErrorHandling(this.name, flag.ContinueOnError)
+	// the flags before an unsupported field are still defined
+	cmd.Init(reflect.New(t).Interface())
+	return cmd.FlagSet
+}
+
+// extractFlag looks up the given flag in the arguments of top command,
+// returns its value and the rest arguments without it. The scanning stops at
+// the first non-flag argument which begins a sub-command, and the flags tell
+// which arguments are values of boolean flags
+func extractFlag(args []string, name string, flags *flag.FlagSet) (string,
+	[]string) {
+	rest := make([]string, 0, len(args))
+	value := ""
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		flag := strings.TrimPrefix(strings.TrimPrefix(arg, "-"), "-")
+		if arg == "--" || flag == arg || flag == "" {
+			rest = append(rest, args[i:]...)
+			break
+		}
+
+		if flag == name && i+1 < len(args) {
+			value = args[i+1]
+			i++
+			continue
+		} else if strings.HasPrefix(flag, name+"=") {
+			value = flag[len(name)+1:]
+			continue
+		}
+
+		rest = append(rest, arg)
+		if !strings.Contains(flag, "=") && !isBoolFlag(flags, flag) &&
+			i+1 < len(args) {
+			i++
+			rest = append(rest, args[i])
+		}
+	}
+
+	return value, rest
+}
+
+// isBoolFlag checks if a flag is a boolean flag which has no value argument
+func isBoolFlag(flags *flag.FlagSet, name string) bool {
+	if flags == nil {
+		return false
+	}
+
+	f := flags.Lookup(name)
+	if f == nil {
+		return false
+	}
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
@@ -0,0 +1,254 @@
+/*
+ * Copyright (C) 2017 eschao <esc.chao@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+
+	"github.com/eschao/config/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoaderWithDefaultOrder(t *testing.T) {
+	_, curTestFile, _, _ := runtime.Caller(0)
+	path := filepath.Dir(curTestFile)
+
+	os.Setenv("CONFIG_TEST_APP_PORT", "7070")
+	os.Setenv("CONFIG_TEST_APP_LOG_LEVEL", "error")
+	defer os.Unsetenv("CONFIG_TEST_APP_PORT")
+	defer os.Unsetenv("CONFIG_TEST_APP_LOG_LEVEL")
+
+	assert := assert.New(t)
+	conf := test.AppConfig{}
+	loader := New(WithConfigFile(path+"/test/app.yaml"),
+		WithArgs("app", []string{"-port", "6060", "log", "-path", "/tmp"}))
+	assert.NoError(loader.Load(&conf))
+
+	// name: default -> file
+	assert.Equal("yaml-app", conf.Name)
+	// port: default -> file -> env -> cli
+	assert.Equal(6060, conf.Port)
+	// debug: only provided by default
+	assert.Equal(true, conf.Debug)
+	// log path: file -> cli
+	assert.Equal("/tmp", conf.Log.Path)
+	// log level: file -> env
+	assert.Equal("error", conf.Log.Level)
+}
+
+func TestLoaderWithSources(t *testing.T) {
+	_, curTestFile, _, _ := runtime.Caller(0)
+	path := filepath.Dir(curTestFile)
+
+	os.Setenv("CONFIG_TEST_APP_NAME", "env-app")
+	defer os.Unsetenv("CONFIG_TEST_APP_NAME")
+
+	assert := assert.New(t)
+	conf := test.AppConfig{}
+	loader := New(WithSources(EnvSource, FileSource, DefaultSource),
+		WithConfigFile(path+"/test/app.yaml"))
+	assert.NoError(loader.Load(&conf))
+	assert.Equal("test-app", conf.Name)
+	assert.Equal(8080, conf.Port)
+	assert.Equal("/var/log/app", conf.Log.Path)
+
+	conf = test.AppConfig{}
+	loader = New(WithSources(DefaultSource, EnvSource))
+	assert.NoError(loader.Load(&conf))
+	assert.Equal("env-app", conf.Name)
+	assert.Equal(8080, conf.Port)
+}
+
+func TestLoaderWithConfigFlag(t *testing.T) {
+	_, curTestFile, _, _ := runtime.Caller(0)
+	path := filepath.Dir(curTestFile)
+
+	assert := assert.New(t)
+	conf := test.AppConfig{}
+	loader := New(WithConfigFlag("c"), WithArgs("app",
+		[]string{"-c", path + "/test/app.yaml", "-name", "cli-app"}))
+	assert.NoError(loader.Load(&conf))
+	assert.Equal("cli-app", conf.Name)
+	assert.Equal(9090, conf.Port)
+
+	conf = test.AppConfig{}
+	loader = New(WithConfigFlag("c"), WithArgs("app",
+		[]string{"--c=" + path + "/test/app.yaml"}))
+	assert.NoError(loader.Load(&conf))
+	assert.Equal("yaml-app", conf.Name)
+
+	// the flag of sub-command with the same name is not extracted
+	conf = test.AppConfig{}
+	loader = New(WithSources(DefaultSource, CliSource), WithConfigFlag("path"),
+		WithArgs("app", []string{"-debug", "log", "-path", "/tmp"}))
+	assert.NoError(loader.Load(&conf))
+	assert.Equal("/tmp", conf.Log.Path)
+}
+
+func TestExtractFlag(t *testing.T) {
+	assert := assert.New(t)
+	conf := test.AppConfig{}
+	flags := New().commandFlags(reflect.TypeOf(conf))
+
+	value, rest := extractFlag([]string{"-port", "c", "-debug", "-c", "x.yaml",
+		"log", "-c", "y.yaml"}, "c", flags)
+	assert.Equal("x.yaml", value)
+	assert.Equal([]string{"-port", "c", "-debug", "log", "-c", "y.yaml"}, rest)
+
+	value, rest = extractFlag([]string{"--c=x.yaml", "--", "-c", "y.yaml"},
+		"c", flags)
+	assert.Equal("x.yaml", value)
+	assert.Equal([]string{"--", "-c", "y.yaml"}, rest)
+
+	value, rest = extractFlag([]string{"log", "-c", "y.yaml"}, "c", flags)
+	assert.Equal("", value)
+	assert.Equal([]string{"log", "-c", "y.yaml"}, rest)
+}
+
+func TestLoaderWithConfigFiles(t *testing.T) {
+	_, curTestFile, _, _ := runtime.Caller(0)
+	path := filepath.Dir(curTestFile)
+
+	override := filepath.Join(t.TempDir(), "override.json")
+	assert := assert.New(t)
+	assert.NoError(os.WriteFile(override,
+		[]byte(`{"port": 7070, "log": {"level": "error"}}`), 0644))
+
+	conf := test.AppConfig{}
+	loader := New(WithSources(DefaultSource, FileSource),
+		WithConfigFiles(path+"/test/app.yaml", override))
+	assert.NoError(loader.Load(&conf))
+	assert.Equal("yaml-app", conf.Name)
+	assert.Equal(7070, conf.Port)
+	assert.Equal(true, conf.Debug)
+	assert.Equal("/var/log/app", conf.Log.Path)
+	assert.Equal("error", conf.Log.Level)
+}
+
+func TestLoaderWithProfile(t *testing.T) {
+	_, curTestFile, _, _ := runtime.Caller(0)
+	path := filepath.Dir(curTestFile)
+
+	assert := assert.New(t)
+	conf := test.AppConfig{}
+	loader := New(WithSources(FileSource), WithProfile("prod"),
+		WithConfigFile(path+"/test/app.yaml"))
+	assert.NoError(loader.Load(&conf))
+	assert.Equal("yaml-app", conf.Name)
+	assert.Equal(443, conf.Port)
+	assert.Equal("/var/log/app", conf.Log.Path)
+	assert.Equal("error", conf.Log.Level)
+
+	// command line flag has higher priority than environment variable
+	os.Setenv(ProfileEnv, "dev")
+	defer os.Unsetenv(ProfileEnv)
+	conf = test.AppConfig{}
+	loader = New(WithSources(FileSource, CliSource), WithProfileFlag("profile"),
+		WithConfigFile(path+"/test/app.yaml"),
+		WithArgs("app", []string{"-profile", "prod"}))
+	assert.NoError(loader.Load(&conf))
+	assert.Equal(443, conf.Port)
+
+	// profile file of dev doesn't exist
+	conf = test.AppConfig{}
+	loader = New(WithSources(FileSource), WithProfile("prod"),
+		WithConfigFile(path+"/test/app.yaml"))
+	assert.NoError(loader.Load(&conf))
+	assert.Equal(9090, conf.Port)
+	assert.Equal("warning", conf.Log.Level)
+}
+
+func TestLoaderWithStrict(t *testing.T) {
+	_, curTestFile, _, _ := runtime.Caller(0)
+	path := filepath.Dir(curTestFile)
+
+	os.Setenv("CONFIG_TEST_APP_LOG_LEVLE", "error")
+	os.Setenv("CONFIG_TEST_STRICT_LOG_LEVLE", "error")
+	defer os.Unsetenv("CONFIG_TEST_APP_LOG_LEVLE")
+	defer os.Unsetenv("CONFIG_TEST_STRICT_LOG_LEVLE")
+
+	assert := assert.New(t)
+	conf := test.AppConfig{}
+	loader := New(WithConfigFile(path+"/test/app.yaml"), WithArgs("app", nil))
+	assert.NoError(loader.Load(&conf))
+
+	// environment variables are not checked without prefix
+	conf = test.AppConfig{}
+	loader = New(WithConfigFile(path+"/test/app.yaml"), WithArgs("app", nil),
+		WithStrict())
+	assert.NoError(loader.Load(&conf))
+
+	dbConf := test.DBConfig{}
+	loader = New(WithSources(EnvSource), WithEnvPrefix("CONFIG_TEST_STRICT_"),
+		WithStrict())
+	err := loader.Load(&dbConf)
+	assert.Error(err)
+
+	var errs Errors
+	assert.True(errors.As(err, &errs))
+	assert.Equal(1, len(errs))
+	assert.Equal(EnvSource, errs[0].(*SourceError).Source)
+	assert.Contains(err.Error(), "CONFIG_TEST_STRICT_LOG_LEVLE")
+}
+
+func TestLoaderErrors(t *testing.T) {
+	os.Setenv("CONFIG_TEST_APP_PORT", "xxx")
+	defer os.Unsetenv("CONFIG_TEST_APP_PORT")
+
+	assert := assert.New(t)
+	conf := test.AppConfig{}
+	loader := New(WithConfigFile("not-exist.yaml"), WithArgs("app", nil))
+	err := loader.Load(&conf)
+	assert.Error(err)
+
+	var errs Errors
+	assert.True(errors.As(err, &errs))
+	assert.Equal(2, len(errs))
+	assert.Equal(FileSource, errs[0].(*SourceError).Source)
+	assert.Equal(EnvSource, errs[1].(*SourceError).Source)
+
+	// all other sources are still loaded
+	assert.Equal("test-app", conf.Name)
+
+	assert.Error(loader.Load(conf))
+}
+
+func TestLoaderCliErrors(t *testing.T) {
+	assert := assert.New(t)
+	conf := test.AppConfig{}
+	loader := New(WithSources(DefaultSource, CliSource),
+		WithArgs("app", []string{"-prot", "6060"}))
+	err := loader.Load(&conf)
+	assert.Error(err)
+
+	var errs Errors
+	assert.True(errors.As(err, &errs))
+	assert.Equal(1, len(errs))
+	assert.Equal(CliSource, errs[0].(*SourceError).Source)
+	assert.Contains(err.Error(), "prot")
+
+	loader = New(WithSources(DefaultSource, CliSource),
+		WithArgs("app", []string{"log", "-level"}))
+	err = loader.Load(&conf)
+	assert.True(errors.As(err, &errs))
+	assert.Equal(CliSource, errs[0].(*SourceError).Source)
+	assert.Equal("test-app", conf.Name)
+}
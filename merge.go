@@ -0,0 +1,380 @@
+/*
+ * Copyright (C) 2017 eschao <esc.chao@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+)
+
+// fieldSet records the fields provided by a configuration, keyed by Go field
+// name. A nil fieldSet of a field means the whole field is provided, and a
+// non-nil one means only the recorded fields of the nested structure are
+// provided
+type fieldSet map[string]fieldSet
+
+// ParseConfigFiles parses given structure interface and deep-merges the
+// specified configuration files into it in order. The files could be in
+// different types, e.g: a JSON base file with a Yaml override file. Only the
+// configurations provided by a file are merged:
+//   - nested structures are merged field by field
+//   - maps are merged key by key
+//   - slices are replaced, or appended if field has merge:"append" tag
+//   - other values are replaced
+//
+// A file could include other files, which are merged before the file's own
+// configurations, see IncludeKey and IncludeDirective
+func ParseConfigFiles(i interface{}, configFiles ...string) error {
+	valueOfStruct, err := structValueOf(i)
+	if err != nil {
+		return err
+	}
+
+	return (&merger{}).mergeFiles(valueOfStruct, configFiles)
+}
+
+// merger merges configuration files into structure value
+type merger struct {
+	origins map[string]Origin // origins of merged fields, nil if not recorded
+	strict  bool              // unknown keys are errors
+}
+
+// mergeFiles merges the configuration files into structure value in order
+func (this *merger) mergeFiles(v reflect.Value, configFiles []string) error {
+	for _, configFile := range configFiles {
+		if err := this.mergeFile(v, configFile, nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// mergeBytes parses configuration data into a new structure value and
+// merges the provided fields into the given structure value
+func mergeBytes(v reflect.Value, data []byte, configType string) error {
+	src := newValueLike(v)
+	if err := ParseBytes(src.Addr().Interface(), data, configType); err != nil {
+		return err
+	}
+
+	fields, err := providedFields(v.Type(), data, configType)
+	if err != nil {
+		return err
+	}
+
+	mergeValue(v, src, fields)
+	return nil
+}
+
+// newValueLike creates a zero structure value of the same type with the
+// given one, and the pointers to structure are allocated if they are not nil
+// in the given one. So the parsers which skip nil pointers could set them
+func newValueLike(v reflect.Value) reflect.Value {
+	n := reflect.New(v.Type()).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		valueOfField := v.Field(i)
+		if !n.Field(i).CanSet() {
+			continue
+		}
+
+		if valueOfField.Kind() == reflect.Ptr && !valueOfField.IsNil() &&
+			valueOfField.Elem().Kind() == reflect.Struct {
+			ptr := reflect.New(valueOfField.Type().Elem())
+			ptr.Elem().Set(newValueLike(valueOfField.Elem()))
+			n.Field(i).Set(ptr)
+		} else if valueOfField.Kind() == reflect.Struct {
+			n.Field(i).Set(newValueLike(valueOfField))
+		}
+	}
+	return n
+}
+
+// mergeValue merges the provided fields of src structure value into dst
+func mergeValue(dst, src reflect.Value, fields fieldSet) {
+	for name, subFields := range fields {
+		structOfField, ok := dst.Type().FieldByName(name)
+		if !ok {
+			continue
+		}
+		mergeField(dst.FieldByIndex(structOfField.Index),
+			src.FieldByIndex(structOfField.Index), subFields,
+			structOfField.Tag.Get("merge") == "append")
+	}
+}
+
+// mergeField merges a field value of src into dst
+func mergeField(dst, src reflect.Value, subFields fieldSet, appending bool) {
+	if subFields != nil {
+		if dst.Kind() == reflect.Ptr {
+			if src.IsNil() {
+				dst.Set(src)
+				return
+			}
+			if dst.IsNil() {
+				dst.Set(reflect.New(dst.Type().Elem()))
+			}
+			dst, src = dst.Elem(), src.Elem()
+		}
+		mergeValue(dst, src, subFields)
+		return
+	}
+
+	switch dst.Kind() {
+	case reflect.Map:
+		dst.Set(mergeMap(dst, src))
+	case reflect.Slice:
+		if appending && !src.IsNil() {
+			dst.Set(reflect.AppendSlice(dst, src))
+		} else {
+			dst.Set(src)
+		}
+	default:
+		dst.Set(src)
+	}
+}
+
+// mergeMap returns a map merged from dst and src by keys, the map values
+// are merged as well if they are maps
+func mergeMap(dst, src reflect.Value) reflect.Value {
+	if dst.Kind() == reflect.Interface {
+		dst = dst.Elem()
+	}
+	if src.Kind() == reflect.Interface {
+		src = src.Elem()
+	}
+
+	if !dst.IsValid() || !src.IsValid() || dst.Kind() != reflect.Map ||
+		src.Kind() != reflect.Map || dst.Type() != src.Type() ||
+		dst.IsNil() || src.IsNil() {
+		return src
+	}
+
+	merged := reflect.MakeMapWithSize(dst.Type(), dst.Len()+src.Len())
+	for _, key := range dst.MapKeys() {
+		merged.SetMapIndex(key, dst.MapIndex(key))
+	}
+
+	for _, key := range src.MapKeys() {
+		value := src.MapIndex(key)
+		if old := merged.MapIndex(key); old.IsValid() {
+			value = mergeMap(old, value)
+		}
+		merged.SetMapIndex(key, value)
+	}
+	return merged
+}
+
+// providedFields returns the fields of the given structure type which are
+// provided by configuration data
+func providedFields(t reflect.Type, data []byte, configType string) (fieldSet,
+	error) {
+	switch configType {
+	case JSONConfigType:
+		var tree map[string]interface{}
+		if err := json.Unmarshal(data, &tree); err != nil {
+			return nil, err
+		}
+		return treeFields(t, tree, "json", true), nil
+	case YamlConfigType:
+		var tree map[interface{}]interface{}
+		if err := yaml.Unmarshal(data, &tree); err != nil {
+			return nil, err
+		}
+		return treeFields(t, stringKeys(tree), "yaml", false), nil
+	case TomlConfigType:
+		var tree map[string]interface{}
+		if _, err := toml.Decode(string(data), &tree); err != nil {
+			return nil, err
+		}
+		return treeFields(t, tree, "toml", true), nil
+	case PropConfigType:
+		props, err := readProperties(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		return keyFields(t, "prop", "", func(key string) bool {
+			_, ok := props[key]
+			return ok
+		}), nil
+	case IniConfigType:
+		values, err := readIni(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		return keyFields(t, "ini", "", func(key string) bool {
+			_, ok := values[key]
+			return ok
+		}), nil
+	}
+
+	return nil, fmt.Errorf("Can't support config type: %s", configType)
+}
+
+// treeFields returns the fields of structure type which are provided by a
+// decoded JSON, Yaml or TOML tree. The fold is used to match names case
+// insensitively if no exact one is found, like JSON and TOML decoders do
+func treeFields(t reflect.Type, tree map[string]interface{}, tag string,
+	fold bool) fieldSet {
+	fields := make(fieldSet)
+	for i := 0; i < t.NumField(); i++ {
+		structOfField := t.Field(i)
+		if structOfField.PkgPath != "" {
+			continue
+		}
+
+		name, ok := treeName(structOfField, tag)
+		if !ok {
+			continue
+		}
+
+		typeOfField := structOfField.Type
+		if typeOfField.Kind() == reflect.Ptr {
+			typeOfField = typeOfField.Elem()
+		}
+
+		// the fields of embedded structure are promoted
+		if name == "" {
+			if typeOfField.Kind() == reflect.Struct {
+				if sub := treeFields(typeOfField, tree, tag, fold); len(sub) > 0 {
+					fields[structOfField.Name] = sub
+				}
+			}
+			continue
+		}
+
+		value, ok := lookupTree(tree, name, fold)
+		if !ok {
+			continue
+		}
+
+		if subTree, ok := value.(map[string]interface{}); ok &&
+			typeOfField.Kind() == reflect.Struct {
+			fields[structOfField.Name] = treeFields(typeOfField, subTree, tag,
+				fold)
+		} else {
+			fields[structOfField.Name] = nil
+		}
+	}
+	return fields
+}
+
+// treeName returns the key name of a field in JSON, Yaml or TOML tree. An
+// empty name means the fields of embedded structure are promoted, and false
+// means the field is ignored
+func treeName(f reflect.StructField, tag string) (string, bool) {
+	value := f.Tag.Get(tag)
+	name := value
+	if index := strings.Index(value, ","); index >= 0 {
+		name = value[:index]
+	}
+
+	if name == "-" {
+		return "", false
+	}
+
+	if name == "" {
+		inline := strings.Contains(value, ",inline")
+		if f.Anonymous && (tag != "yaml" || inline) {
+			return "", true
+		}
+
+		name = f.Name
+		if tag == "yaml" {
+			name = strings.ToLower(name)
+		}
+	}
+	return name, true
+}
+
+// lookupTree looks up value by name in the tree
+func lookupTree(tree map[string]interface{}, name string,
+	fold bool) (interface{}, bool) {
+	if value, ok := tree[name]; ok {
+		return value, true
+	}
+
+	if fold {
+		for key, value := range tree {
+			if strings.EqualFold(key, name) {
+				return value, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// stringKeys converts the keys of Yaml maps to string recursively
+func stringKeys(tree map[interface{}]interface{}) map[string]interface{} {
+	converted := make(map[string]interface{}, len(tree))
+	for key, value := range tree {
+		converted[fmt.Sprint(key)] = stringKeyValue(value)
+	}
+	return converted
+}
+
+func stringKeyValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		return stringKeys(v)
+	case []interface{}:
+		values := make([]interface{}, len(v))
+		for i, e := range v {
+			values[i] = stringKeyValue(e)
+		}
+		return values
+	}
+	return value
+}
+
+// keyFields returns the fields of structure type which are provided by flat
+// keys of Properties or INI, the tag of a nested structure is joined with
+// the tags of its fields by '.'
+func keyFields(t reflect.Type, tag string, prefix string,
+	has func(string) bool) fieldSet {
+	fields := make(fieldSet)
+	for i := 0; i < t.NumField(); i++ {
+		structOfField := t.Field(i)
+		if structOfField.PkgPath != "" {
+			continue
+		}
+
+		name := structOfField.Tag.Get(tag)
+		typeOfField := structOfField.Type
+		if typeOfField.Kind() == reflect.Ptr {
+			typeOfField = typeOfField.Elem()
+		}
+
+		if typeOfField.Kind() == reflect.Struct {
+			subPrefix := prefix
+			if name != "" {
+				subPrefix = prefix + name + "."
+			}
+			if sub := keyFields(typeOfField, tag, subPrefix, has); len(sub) > 0 {
+				fields[structOfField.Name] = sub
+			}
+		} else if name != "" && has(prefix+name) {
+			fields[structOfField.Name] = nil
+		}
+	}
+	return fields
+}
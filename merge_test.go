@@ -0,0 +1,106 @@
+/*
+ * Copyright (C) 2017 eschao <esc.chao@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/eschao/config/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseConfigFiles(t *testing.T) {
+	_, curTestFile, _, _ := runtime.Caller(0)
+	path := filepath.Dir(curTestFile)
+
+	conf := test.MergeConfig{}
+	assert := assert.New(t)
+	assert.NoError(ParseConfigFiles(&conf, path+"/test/merge.json",
+		path+"/test/merge.yaml"))
+
+	// scalars not in the override are kept
+	assert.Equal("base", conf.Name)
+	// slices are appended with merge:"append" tag, otherwise replaced
+	assert.Equal([]string{"auth", "metrics", "tracing"}, conf.Plugins)
+	assert.Equal([]string{"c.example.com"}, conf.Hosts)
+	// maps are merged by keys
+	assert.Equal(map[string]string{"team": "config", "tier": "frontend"},
+		conf.Labels)
+	// slice elements don't keep stale values from the base file
+	assert.Equal([]test.ServerConfig{{Host: "override-host"}}, conf.Servers)
+
+	// nested structures are merged field by field
+	assert.Equal("base-db-host", conf.DB.Host)
+	assert.Equal(6543, conf.DB.Port)
+	assert.Equal("/var/log/db", conf.DB.Log.Path)
+	assert.Equal("error", conf.DB.Log.Level)
+	assert.NotNil(conf.Log)
+	assert.Equal("/var/log/base", conf.Log.Path)
+	assert.Equal("warning", conf.Log.Level)
+}
+
+func TestParseConfigFilesWithMixedTypes(t *testing.T) {
+	_, curTestFile, _, _ := runtime.Caller(0)
+	path := filepath.Dir(curTestFile)
+
+	dir := t.TempDir()
+	override := filepath.Join(dir, "override.properties")
+	assert := assert.New(t)
+	assert.NoError(os.WriteFile(override,
+		[]byte("dbPort = 6543\nlog.level = debug\n"), 0644))
+
+	conf := test.DBConfig{}
+	assert.NoError(ParseConfigFiles(&conf, path+"/test/config.json",
+		path+"/test/config.toml", override))
+	assert.Equal(DB_HOST, conf.Host)
+	assert.Equal(6543, conf.Port)
+	assert.Equal(DB_USER, conf.User)
+	assert.Equal(DB_PASSWORD, conf.Password)
+	assert.Equal(DB_LOG_PATH, conf.Log.Path)
+	assert.Equal("debug", conf.Log.Level)
+}
+
+func TestParseConfigFilesWithError(t *testing.T) {
+	_, curTestFile, _, _ := runtime.Caller(0)
+	path := filepath.Dir(curTestFile)
+
+	conf := test.DBConfig{}
+	assert := assert.New(t)
+	assert.Error(ParseConfigFiles(&conf, path+"/test/config.json",
+		path+"/test/not-exist.yaml"))
+	assert.Error(ParseConfigFiles(&conf, path+"/test/data.go"))
+	assert.Error(ParseConfigFiles(conf, path+"/test/config.json"))
+}
+
+func TestMergeMap(t *testing.T) {
+	conf := struct {
+		Values map[string]interface{} `yaml:"values"`
+	}{}
+
+	assert := assert.New(t)
+	v, _ := structValueOf(&conf)
+	assert.NoError(mergeBytes(v, []byte("values:\n  a:\n    host: h1\n    port: 2\n"),
+		YamlConfigType))
+	assert.NoError(mergeBytes(v, []byte("values:\n  a:\n    port: 3\n  b: 4\n"),
+		YamlConfigType))
+	assert.Equal(map[string]interface{}{
+		"a": map[interface{}]interface{}{"host": "h1", "port": 3},
+		"b": 4,
+	}, conf.Values)
+}
@@ -0,0 +1,293 @@
+/*
+ * Copyright (C) 2017 eschao <esc.chao@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+// Position is a line and column in a configuration file, both begin at 1
+type Position struct {
+	Line   int
+	Column int
+}
+
+// keyPositions returns the positions of keys in configuration data, keyed by
+// the key names of nested maps or sections joined by '.', e.g: log.level.
+// The keys in arrays are not included
+func keyPositions(data []byte, configType string) map[string]Position {
+	positions := make(map[string]Position)
+	switch configType {
+	case JSONConfigType:
+		jsonPositions(data, positions)
+	case YamlConfigType:
+		yamlPositions(data, positions)
+	default:
+		linePositions(data, configType, positions)
+	}
+	return positions
+}
+
+// lookupPosition looks up position of a key, the key is matched case
+// insensitively if no exact one is found
+func lookupPosition(positions map[string]Position, key string) (Position,
+	bool) {
+	if position, ok := positions[key]; ok {
+		return position, true
+	}
+
+	for k, position := range positions {
+		if strings.EqualFold(k, key) {
+			return position, true
+		}
+	}
+	return Position{}, false
+}
+
+// jsonPositions records the positions of JSON object keys
+func jsonPositions(data []byte, positions map[string]Position) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	var walk func(prefix string, record bool) error
+	walk = func(prefix string, record bool) error {
+		token, err := dec.Token()
+		if err != nil {
+			return err
+		}
+
+		delim, ok := token.(json.Delim)
+		if !ok {
+			return nil
+		}
+
+		for dec.More() {
+			if delim == '[' {
+				if err := walk("", false); err != nil {
+					return err
+				}
+				continue
+			}
+
+			offset := int(dec.InputOffset())
+			token, err := dec.Token()
+			if err != nil {
+				return err
+			}
+
+			key := joinPath(prefix, token.(string), ".")
+			if record {
+				// skip the separators before the key
+				for offset < len(data) && data[offset] != '"' {
+					offset++
+				}
+				positions[key] = offsetPosition(data, offset)
+			}
+
+			if err := walk(key, record); err != nil {
+				return err
+			}
+		}
+
+		// the closing delimiter
+		_, err = dec.Token()
+		return err
+	}
+
+	walk("", true)
+}
+
+// offsetPosition returns the position of a byte offset in data
+func offsetPosition(data []byte, offset int) Position {
+	line := bytes.Count(data[:offset], []byte("\n")) + 1
+	column := offset - bytes.LastIndexByte(data[:offset], '\n')
+	return Position{Line: line, Column: column}
+}
+
+// yamlPositions records the positions of Yaml block mapping keys by their
+// indents. The keys in sequences, flow collections and block scalars are
+// not included
+func yamlPositions(data []byte, positions map[string]Position) {
+	type key struct {
+		indent int
+		name   string
+	}
+
+	var parents []key
+	skipIndent := -1 // the lines indented more than it are skipped
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimRight(line, "\r")
+		text := strings.TrimLeft(line, " ")
+		indent := len(line) - len(text)
+		if text == "" || text[0] == '#' {
+			continue
+		}
+
+		if skipIndent >= 0 && indent > skipIndent {
+			continue
+		}
+		skipIndent = -1
+
+		if text == "---" || text == "..." {
+			parents = nil
+			continue
+		}
+
+		if text[0] == '-' && (len(text) == 1 || text[1] == ' ') {
+			skipIndent = indent
+			continue
+		}
+
+		name, value, ok := splitYamlKey(text)
+		if !ok {
+			continue
+		}
+
+		for len(parents) > 0 && parents[len(parents)-1].indent >= indent {
+			parents = parents[:len(parents)-1]
+		}
+		path := name
+		if len(parents) > 0 {
+			path = joinPath(parents[len(parents)-1].name, name, ".")
+		}
+		positions[path] = Position{Line: i + 1, Column: indent + 1}
+		parents = append(parents, key{indent: indent, name: path})
+
+		if value != "" && (value[0] == '|' || value[0] == '>') {
+			skipIndent = indent
+		}
+	}
+}
+
+// splitYamlKey splits a Yaml mapping line into its key and value without
+// comment, it returns false if the line is not a mapping key
+func splitYamlKey(text string) (string, string, bool) {
+	var name, rest string
+	if text[0] == '"' || text[0] == '\'' {
+		end := strings.IndexByte(text[1:], text[0])
+		if end < 0 || !strings.HasPrefix(text[end+2:], ":") {
+			return "", "", false
+		}
+		name, rest = text[1:end+1], text[end+3:]
+	} else {
+		index := strings.Index(text+" ", ": ")
+		if index <= 0 || strings.ContainsAny(text[:1], "[{&*!|>%@`") {
+			return "", "", false
+		}
+		name, rest = strings.TrimSpace(text[:index]), text[index+1:]
+	}
+
+	if rest != "" && rest[0] != ' ' && rest[0] != '\t' {
+		return "", "", false
+	}
+	rest = strings.TrimSpace(rest)
+	if strings.HasPrefix(rest, "#") {
+		rest = ""
+	}
+	return name, rest, true
+}
+
+// linePositions records the positions of keys in line based configuration
+// data: TOML, Properties and INI
+func linePositions(data []byte, configType string,
+	positions map[string]Position) {
+	section := ""
+	continued := false
+	multiline := ""
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimRight(line, "\r")
+		text := strings.TrimLeft(line, " \t\f")
+		position := Position{Line: i + 1, Column: len(line) - len(text) + 1}
+
+		switch configType {
+		case PropConfigType:
+			if continued {
+				continued = isContinued(text)
+				continue
+			}
+
+			if text == "" || text[0] == '#' || text[0] == '!' {
+				continue
+			}
+
+			continued = isContinued(text)
+			if continued {
+				text = text[:len(text)-1]
+			}
+			if key, _, err := splitProperty(text); err == nil {
+				positions[key] = position
+			}
+		case IniConfigType, TomlConfigType:
+			if multiline != "" {
+				if strings.Contains(text, multiline) {
+					multiline = ""
+				}
+				continue
+			}
+
+			if text == "" || text[0] == ';' || text[0] == '#' {
+				continue
+			}
+
+			if text[0] == '[' {
+				section = strings.Trim(text, "[] \t")
+				if index := strings.Index(section, "]"); index >= 0 {
+					section = strings.TrimSpace(section[:index])
+				}
+				section = unquoteKey(section)
+				continue
+			}
+
+			separators := "="
+			if configType == IniConfigType {
+				separators = "=:"
+			}
+			index := strings.IndexAny(text, separators)
+			if index <= 0 {
+				continue
+			}
+
+			key := unquoteKey(strings.TrimSpace(text[:index]))
+			positions[joinPath(section, key, ".")] = position
+
+			value := text[index+1:]
+			for _, quote := range []string{`"""`, `'''`} {
+				if strings.Count(value, quote)%2 == 1 {
+					multiline = quote
+				}
+			}
+		}
+	}
+}
+
+// isContinued checks if a Properties line continues, that means it ends with
+// an odd number of backslashes
+func isContinued(line string) bool {
+	n := 0
+	for i := len(line) - 1; i >= 0 && line[i] == '\\'; i-- {
+		n++
+	}
+	return n%2 == 1
+}
+
+// unquoteKey removes quotes and spaces around the parts of a dotted key
+func unquoteKey(key string) string {
+	parts := strings.Split(key, ".")
+	for i, part := range parts {
+		parts[i] = strings.Trim(strings.TrimSpace(part), `"'`)
+	}
+	return strings.Join(parts, ".")
+}
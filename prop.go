@@ -0,0 +1,217 @@
+/*
+ * Copyright (C) 2017 eschao <esc.chao@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/eschao/config/utils"
+)
+
+// readProperties reads Java-style properties from the given reader and
+// returns them as a key/value map. It supports:
+//   - comment lines beginning with '#' or '!'
+//   - '=', ':' or whitespace as key/value separator
+//   - line continuations with a trailing '\'
+//   - escape sequences, including unicode escapes: \uXXXX
+func readProperties(r io.Reader) (map[string]string, error) {
+	props := make(map[string]string)
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	logical := ""
+	continued := false
+
+	for scanner.Scan() {
+		lineNo++
+		line := scanner.Text()
+		if continued {
+			line = strings.TrimLeft(line, " \t\f")
+		} else {
+			line = strings.TrimLeft(line, " \t\f")
+			if line == "" || line[0] == '#' || line[0] == '!' {
+				continue
+			}
+		}
+
+		if isContinued(line) {
+			logical += line[:len(line)-1]
+			continued = true
+			continue
+		}
+
+		logical += line
+		continued = false
+		key, value, err := splitProperty(logical)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", lineNo, err.Error())
+		}
+		props[key] = value
+		logical = ""
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if continued {
+		key, value, err := splitProperty(logical)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", lineNo, err.Error())
+		}
+		props[key] = value
+	}
+
+	return props, nil
+}
+
+// splitProperty splits a logical line into unescaped key and value
+func splitProperty(line string) (string, string, error) {
+	end := len(line)
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		if c == '\\' {
+			i++
+			continue
+		}
+		if c == '=' || c == ':' || c == ' ' || c == '\t' || c == '\f' {
+			end = i
+			break
+		}
+	}
+
+	rest := strings.TrimLeft(line[end:], " \t\f")
+	if rest != "" && (rest[0] == '=' || rest[0] == ':') {
+		rest = strings.TrimLeft(rest[1:], " \t\f")
+	}
+
+	key, err := unescapeProperty(line[:end])
+	if err != nil {
+		return "", "", err
+	}
+
+	value, err := unescapeProperty(rest)
+	if err != nil {
+		return "", "", err
+	}
+
+	return key, value, nil
+}
+
+// unescapeProperty replaces escape sequences in a properties key or value
+func unescapeProperty(s string) (string, error) {
+	if !strings.Contains(s, "\\") {
+		return s, nil
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c != '\\' || i+1 >= len(s) {
+			b.WriteByte(c)
+			continue
+		}
+
+		i++
+		switch s[i] {
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 'f':
+			b.WriteByte('\f')
+		case 'u':
+			if i+4 >= len(s) {
+				return "", fmt.Errorf("Malformed \\uXXXX encoding: %s", s)
+			}
+			r, err := strconv.ParseUint(s[i+1:i+5], 16, 32)
+			if err != nil {
+				return "", fmt.Errorf("Malformed \\uXXXX encoding: %s", s)
+			}
+			b.WriteRune(rune(r))
+			i += 4
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+
+	return b.String(), nil
+}
+
+// setPropValue sets structure with properties. The prop tag of a nested
+// structure is joined with the tags of its fields by '.', e.g: log.path
+func setPropValue(v reflect.Value, props map[string]string,
+	prefix string) error {
+	typeOfStruct := v.Type()
+	for i := 0; i < v.NumField(); i++ {
+		valueOfField := v.Field(i)
+		kindOfField := valueOfField.Kind()
+		structOfField := typeOfStruct.Field(i)
+		propName := structOfField.Tag.Get("prop")
+
+		subPrefix := prefix
+		if propName != "" {
+			subPrefix = prefix + propName + "."
+		}
+
+		if kindOfField == reflect.Ptr {
+			if valueOfField.IsNil() || !valueOfField.CanSet() ||
+				valueOfField.Elem().Kind() != reflect.Struct {
+				continue
+			}
+			if err := setPropValue(valueOfField.Elem(), props,
+				subPrefix); err != nil {
+				return err
+			}
+			continue
+		} else if kindOfField == reflect.Struct {
+			if err := setPropValue(valueOfField, props, subPrefix); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if propName == "" {
+			continue
+		}
+
+		propValue, ok := props[prefix+propName]
+		if !ok {
+			continue
+		}
+
+		if !valueOfField.CanSet() {
+			return fmt.Errorf("%s: can't be set", structOfField.Name)
+		}
+
+		sp, ok := structOfField.Tag.Lookup("separator")
+		if !ok {
+			sp = ":"
+		}
+
+		if err := utils.SetValue(valueOfField, propValue, sp); err != nil {
+			return fmt.Errorf("%s: %s", prefix+propName, err.Error())
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,88 @@
+/*
+ * Copyright (C) 2017 eschao <esc.chao@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/eschao/config/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadProperties(t *testing.T) {
+	data := `
+# comment line
+  ! another comment line
+key1=value1
+key2 : value2
+key3   value3
+key4=
+key\ 5 = value\=5
+key6 = first \
+       second \
+       third
+key7 = \u4e2d\u6587\tend
+key8 = c:\\path\\to
+`
+	assert := assert.New(t)
+	props, err := readProperties(strings.NewReader(data))
+	assert.NoError(err)
+	assert.Equal(8, len(props))
+	assert.Equal("value1", props["key1"])
+	assert.Equal("value2", props["key2"])
+	assert.Equal("value3", props["key3"])
+	assert.Equal("", props["key4"])
+	assert.Equal("value=5", props["key 5"])
+	assert.Equal("first second third", props["key6"])
+	assert.Equal("中文\tend", props["key7"])
+	assert.Equal(`c:\path\to`, props["key8"])
+
+	_, err = readProperties(strings.NewReader(`key = \u12`))
+	assert.Error(err)
+}
+
+func TestSetPropValue(t *testing.T) {
+	type propConfig struct {
+		Paths  []string          `prop:"paths"`
+		Values []int             `prop:"values" separator:","`
+		Login  *test.LoginConfig `prop:"login"`
+		Log    test.LogConfig
+	}
+
+	props := map[string]string{
+		"paths":          "/var:/usr",
+		"values":         "1,2,3",
+		"login.user":     "test-user",
+		"login.password": "test-passwd",
+		"path":           "/var/log",
+		"level":          "debug",
+	}
+
+	assert := assert.New(t)
+	conf := propConfig{Login: &test.LoginConfig{}}
+	assert.NoError(setPropValue(reflect.ValueOf(&conf).Elem(), props, ""))
+	assert.Equal([]string{"/var", "/usr"}, conf.Paths)
+	assert.Equal([]int{1, 2, 3}, conf.Values)
+	assert.Equal("test-user", conf.Login.User)
+	assert.Equal("test-passwd", conf.Login.Password)
+	assert.Equal("/var/log", conf.Log.Path)
+	assert.Equal("debug", conf.Log.Level)
+
+	props["values"] = "1,x"
+	assert.Error(setPropValue(reflect.ValueOf(&conf).Elem(), props, ""))
+}
@@ -0,0 +1,264 @@
+/*
+ * Copyright (C) 2017 eschao <esc.chao@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/eschao/config/env"
+)
+
+// Origin describes where the final value of a field comes from
+type Origin struct {
+	Source Source
+	Name   string // key in file, environment variable or command line flag
+	File   string // configuration file, or the file of a _FILE variable
+	Position
+}
+
+func (this Origin) String() string {
+	switch this.Source {
+	case "":
+		return "not set"
+	case DefaultSource:
+		return string(this.Source)
+	case FileSource:
+		file := this.File
+		if this.Line > 0 {
+			file = fmt.Sprintf("%s:%d:%d", file, this.Line, this.Column)
+		}
+		if this.Name != "" {
+			file += " (" + this.Name + ")"
+		}
+		return "file " + file
+	}
+
+	s := string(this.Source)
+	if this.Name != "" {
+		s += " " + this.Name
+	}
+	if this.File != "" {
+		s += " (" + this.File + ")"
+	}
+	return s
+}
+
+// FieldOrigin is the origin of a field value
+type FieldOrigin struct {
+	Path   string
+	Value  interface{}
+	Origin Origin
+}
+
+// Provenance is the origins of all fields of a configuration structure
+type Provenance []FieldOrigin
+
+// Lookup returns the origin of a field by its Go path, e.g: Log.Level
+func (this Provenance) Lookup(path string) (Origin, bool) {
+	for _, f := range this {
+		if f.Path == path {
+			return f.Origin, true
+		}
+	}
+	return Origin{}, false
+}
+
+func (this Provenance) String() string {
+	lines := make([]string, len(this))
+	for i, f := range this {
+		lines[i] = fmt.Sprintf("%s = %v <- %s", f.Path, f.Value, f.Origin)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// LoadWithProvenance loads all sources into given structure pointer like
+// Load, and returns the origins of all field values. A field is "not set" if
+// no source sets it. The values of secret fields are masked, see Dump
+func (this *Loader) LoadWithProvenance(i interface{}) (Provenance, error) {
+	origins, err := this.load(i)
+	if origins == nil {
+		return nil, err
+	}
+	return explain(reflect.ValueOf(i).Elem(), origins), err
+}
+
+// Explain returns the origins of all field values of given structure pointer
+// which is loaded by the last Load of the Loader. The values are the current
+// ones, and the values of secret fields are masked, see Dump
+func (this *Loader) Explain(i interface{}) (Provenance, error) {
+	this.mutex.Lock()
+	loaded, origins := this.loaded, this.origins
+	this.mutex.Unlock()
+
+	if loaded == nil || loaded != i {
+		return nil, fmt.Errorf(
+			"Can't explain a configuration not loaded by the last Load")
+	}
+	return explain(reflect.ValueOf(i).Elem(), origins), nil
+}
+
+// Explain loads given structure pointer by a Loader with the options, and
+// returns the origins of all field values, see Loader.Explain
+func Explain(i interface{}, opts ...Option) (Provenance, error) {
+	return New(opts...).LoadWithProvenance(i)
+}
+
+// record records the origins of the last load
+func (this *Loader) record(i interface{}, origins map[string]Origin) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.loaded, this.origins = i, origins
+}
+
+// explain returns the origins of all field values of structure value
+func explain(v reflect.Value, origins map[string]Origin) Provenance {
+	var provenance Provenance
+	secrets := make(secretFields)
+	walkFields(v, "", func(f *field) error {
+		secret := secrets.check(f)
+		if isStructField(f.field) {
+			return nil
+		}
+
+		value := f.value.Interface()
+		if secret {
+			value = SecretMask
+		}
+		provenance = append(provenance, FieldOrigin{
+			Path:   f.path,
+			Value:  value,
+			Origin: lookupOrigin(origins, f.path),
+		})
+		return nil
+	})
+	return provenance
+}
+
+// lookupOrigin returns the origin of a field, or the origin of its nearest
+// parent structure if the whole structure is set
+func lookupOrigin(origins map[string]Origin, path string) Origin {
+	for {
+		if origin, ok := origins[path]; ok {
+			return origin
+		}
+
+		index := strings.LastIndex(path, ".")
+		if index < 0 {
+			return Origin{}
+		}
+		path = path[:index]
+	}
+}
+
+// setOrigin records the origin of a field, the origins of its nested fields
+// are overridden
+func setOrigin(origins map[string]Origin, path string, origin Origin) {
+	for p := range origins {
+		if strings.HasPrefix(p, path+".") {
+			delete(origins, p)
+		}
+	}
+	origins[path] = origin
+}
+
+// fileOrigins records the origins of fields provided by a configuration
+// file
+func fileOrigins(v reflect.Value, file string, data []byte, configType string,
+	origins map[string]Origin) {
+	fields, err := providedFields(v.Type(), data, configType)
+	if err != nil {
+		return
+	}
+
+	tag := configType
+	if configType == PropConfigType {
+		tag = "prop"
+	}
+
+	names := make(map[string]string)
+	walkFields(v, "", func(f *field) error {
+		names[f.path] = f.names[tag]
+		return nil
+	})
+
+	positions := keyPositions(data, configType)
+	var record func(prefix string, fields fieldSet)
+	record = func(prefix string, fields fieldSet) {
+		for name, subFields := range fields {
+			path := joinPath(prefix, name, ".")
+			if subFields != nil {
+				record(path, subFields)
+				continue
+			}
+
+			origin := Origin{Source: FileSource, Name: names[path], File: file}
+			if origin.Name == "" {
+				origin.Name = path
+			}
+			if position, ok := lookupPosition(positions,
+				origin.Name); ok {
+				origin.Position = position
+			}
+			setOrigin(origins, path, origin)
+		}
+	}
+	record("", fields)
+}
+
+// defaultOrigins records the origins of fields which have default values
+func defaultOrigins(v reflect.Value, origins map[string]Origin) {
+	walkFields(v, "", func(f *field) error {
+		if _, ok := f.field.Tag.Lookup("default"); ok && !isStructField(f.field) {
+			setOrigin(origins, f.path, Origin{Source: DefaultSource})
+		}
+		return nil
+	})
+}
+
+// envOrigins records the origins of fields which are set by environment
+// variables or the files of their _FILE variables
+func envOrigins(v reflect.Value, prefix string, origins map[string]Origin) {
+	walkFields(v, prefix, func(f *field) error {
+		name := f.names["env"]
+		if name == "" || isStructField(f.field) {
+			return nil
+		}
+
+		if _, ok := os.LookupEnv(name); ok {
+			setOrigin(origins, f.path, Origin{Source: EnvSource, Name: name})
+		} else if file, ok := os.LookupEnv(name + env.FileSuffix); ok {
+			setOrigin(origins, f.path, Origin{Source: EnvSource,
+				Name: name + env.FileSuffix, File: file})
+		}
+		return nil
+	})
+}
+
+// cliOrigins records the origins of fields which are set by command line
+// flags
+func cliOrigins(v reflect.Value, flags map[string]bool,
+	origins map[string]Origin) {
+	walkFields(v, "", func(f *field) error {
+		name := f.names["cli"]
+		if flags[name] && !isStructField(f.field) {
+			setOrigin(origins, f.path, Origin{Source: CliSource, Name: name})
+		}
+		return nil
+	})
+}
@@ -0,0 +1,203 @@
+/*
+ * Copyright (C) 2017 eschao <esc.chao@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/eschao/config/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadWithProvenance(t *testing.T) {
+	_, curTestFile, _, _ := runtime.Caller(0)
+	path := filepath.Dir(curTestFile)
+
+	os.Setenv("CONFIG_TEST_APP_LOG_LEVEL", "error")
+	defer os.Unsetenv("CONFIG_TEST_APP_LOG_LEVEL")
+
+	assert := assert.New(t)
+	conf := test.AppConfig{}
+	loader := New(WithConfigFile(path+"/test/app.yaml"),
+		WithArgs("app", []string{"-port", "6060"}))
+	provenance, err := loader.LoadWithProvenance(&conf)
+	assert.NoError(err)
+	assert.Equal(5, len(provenance))
+
+	origin, ok := provenance.Lookup("Name")
+	assert.True(ok)
+	assert.Equal(Origin{Source: FileSource, Name: "name",
+		File:     path + "/test/app.yaml",
+		Position: Position{Line: 1, Column: 1}}, origin)
+
+	origin, _ = provenance.Lookup("Port")
+	assert.Equal(Origin{Source: CliSource, Name: "-port"}, origin)
+	assert.Equal("cli -port", origin.String())
+
+	origin, _ = provenance.Lookup("Debug")
+	assert.Equal("default", origin.String())
+
+	origin, _ = provenance.Lookup("Log.Path")
+	assert.Equal(path+"/test/app.yaml:4:3 (log.path)",
+		origin.String()[len("file "):])
+
+	origin, _ = provenance.Lookup("Log.Level")
+	assert.Equal("env CONFIG_TEST_APP_LOG_LEVEL", origin.String())
+
+	assert.Contains(provenance.String(),
+		"Log.Level = error <- env CONFIG_TEST_APP_LOG_LEVEL")
+
+	// no source sets the fields
+	provenance, err = New(WithSources()).LoadWithProvenance(&test.AppConfig{})
+	assert.NoError(err)
+	origin, _ = provenance.Lookup("Name")
+	assert.Equal("not set", origin.String())
+
+	provenance, err = loader.LoadWithProvenance(conf)
+	assert.Error(err)
+	assert.Nil(provenance)
+}
+
+func TestExplain(t *testing.T) {
+	_, curTestFile, _, _ := runtime.Caller(0)
+	path := filepath.Dir(curTestFile)
+
+	assert := assert.New(t)
+	conf := test.AppConfig{}
+	provenance, err := Explain(&conf, WithConfigFile(path+"/test/app.yaml"),
+		WithArgs("app", []string{"-port", "6060"}))
+	assert.NoError(err)
+	origin, _ := provenance.Lookup("Port")
+	assert.Equal("cli -port", origin.String())
+
+	loader := New(WithSources(FileSource),
+		WithConfigFile(path+"/test/app.yaml"))
+	_, err = loader.Explain(&conf)
+	assert.Error(err)
+
+	assert.NoError(loader.Load(&conf))
+	conf.Name = "changed"
+	provenance, err = loader.Explain(&conf)
+	assert.NoError(err)
+	origin, _ = provenance.Lookup("Name")
+	assert.Equal(FileSource, origin.Source)
+	assert.Contains(provenance.String(), "Name = changed <- file ")
+
+	// only the last load is explained
+	assert.NoError(loader.Load(&test.AppConfig{}))
+	_, err = loader.Explain(&conf)
+	assert.Error(err)
+}
+
+func TestProvenanceIncludes(t *testing.T) {
+	_, curTestFile, _, _ := runtime.Caller(0)
+	path := filepath.Dir(curTestFile) + "/test/include"
+
+	assert := assert.New(t)
+	conf := test.MergeConfig{}
+	loader := New(WithSources(FileSource),
+		WithConfigFile(path+"/service.json"))
+	provenance, err := loader.LoadWithProvenance(&conf)
+	assert.NoError(err)
+	origin, _ := provenance.Lookup("Name")
+	assert.Equal(path+"/service.json:3:2 (name)", origin.String()[len("file "):])
+
+	origin, _ = provenance.Lookup("DB.Port")
+	assert.Equal(path+"/service.json:5:3 (db.dbPort)",
+		origin.String()[len("file "):])
+
+	origin, _ = provenance.Lookup("DB.Host")
+	assert.Equal(path+"/database.yaml:2:3 (db.dbHost)",
+		origin.String()[len("file "):])
+
+	conf2 := test.DBConfig{}
+	loader = New(WithSources(FileSource), WithConfigFile(path+"/db.ini"))
+	provenance, err = loader.LoadWithProvenance(&conf2)
+	assert.NoError(err)
+	origin, _ = provenance.Lookup("Port")
+	assert.Equal(path+"/db.properties:2:1 (dbPort)",
+		origin.String()[len("file "):])
+
+	origin, _ = provenance.Lookup("Log.Level")
+	assert.Equal(path+"/db.ini:3:1 (log.level)", origin.String()[len("file "):])
+}
+
+func TestProvenanceFileEnv(t *testing.T) {
+	secret := filepath.Join(t.TempDir(), "name")
+	assert := assert.New(t)
+	assert.NoError(os.WriteFile(secret, []byte("secret-app\n"), 0600))
+
+	os.Setenv("CONFIG_TEST_APP_NAME_FILE", secret)
+	defer os.Unsetenv("CONFIG_TEST_APP_NAME_FILE")
+
+	conf := test.AppConfig{}
+	loader := New(WithSources(DefaultSource, EnvSource))
+	provenance, err := loader.LoadWithProvenance(&conf)
+	assert.NoError(err)
+	assert.Equal("secret-app", conf.Name)
+
+	origin, _ := provenance.Lookup("Name")
+	assert.Equal("env CONFIG_TEST_APP_NAME_FILE ("+secret+")", origin.String())
+}
+
+func TestKeyPositions(t *testing.T) {
+	assert := assert.New(t)
+	positions := keyPositions([]byte("{\n  \"a\": {\"b\": 1},\n  \"c\": [1]\n}"),
+		JSONConfigType)
+	assert.Equal(Position{2, 3}, positions["a"])
+	assert.Equal(Position{2, 9}, positions["a.b"])
+	assert.Equal(Position{3, 3}, positions["c"])
+
+	positions = keyPositions([]byte("a:\n  b: 1\nc: [1]\n"), YamlConfigType)
+	assert.Equal(Position{1, 1}, positions["a"])
+	assert.Equal(Position{2, 3}, positions["a.b"])
+	assert.Equal(Position{3, 1}, positions["c"])
+
+	positions = keyPositions([]byte("# comment\na: # x\n  s: |\n    x: 1\n"+
+		"  l:\n  - y: 2\n  \"q k\": 3\n---\nd: {e: 1}\n"), YamlConfigType)
+	assert.Equal(Position{2, 1}, positions["a"])
+	assert.Equal(Position{3, 3}, positions["a.s"])
+	assert.Equal(Position{5, 3}, positions["a.l"])
+	assert.Equal(Position{7, 3}, positions["a.q k"])
+	assert.Equal(Position{9, 1}, positions["d"])
+	for _, key := range []string{"a.s.x", "x", "y", "a.l.y", "d.e"} {
+		_, ok := positions[key]
+		assert.False(ok, key)
+	}
+
+	positions = keyPositions([]byte("c = 1\ns = \"\"\"\nx = 1\n\"\"\"\n[a]\n  b = 2\n"),
+		TomlConfigType)
+	assert.Equal(Position{1, 1}, positions["c"])
+	_, ok := positions["x"]
+	assert.False(ok)
+	assert.Equal(Position{6, 3}, positions["a.b"])
+
+	positions = keyPositions([]byte("# comment\na.b = 1\\\n  2\nc: 3\n"),
+		PropConfigType)
+	assert.Equal(Position{2, 1}, positions["a.b"])
+	assert.Equal(Position{4, 1}, positions["c"])
+
+	positions = keyPositions([]byte("x = 1\n[a]\nb = 2\n"), IniConfigType)
+	assert.Equal(Position{1, 1}, positions["x"])
+	assert.Equal(Position{3, 1}, positions["a.b"])
+
+	position, ok := lookupPosition(positions, "A.B")
+	assert.True(ok)
+	assert.Equal(Position{3, 1}, position)
+}
@@ -0,0 +1,104 @@
+/*
+ * Copyright (C) 2017 eschao <esc.chao@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// MissingField describes a required field which is not set by any source
+type MissingField struct {
+	Path  string            // Go path of field, e.g: DBConfig.Host
+	Names map[string]string // configuration names keyed by tag, e.g: env
+}
+
+func (this MissingField) String() string {
+	names := make([]string, 0, len(this.Names))
+	for _, tag := range nameTags {
+		if name, ok := this.Names[tag]; ok {
+			names = append(names, tag+": "+name)
+		}
+	}
+
+	if len(names) == 0 {
+		return this.Path
+	}
+	return fmt.Sprintf("%s (%s)", this.Path, strings.Join(names, ", "))
+}
+
+// RequiredError reports all required fields which are not set
+type RequiredError struct {
+	Fields []MissingField
+}
+
+func (this *RequiredError) Error() string {
+	fields := make([]string, len(this.Fields))
+	for i, f := range this.Fields {
+		fields[i] = f.String()
+	}
+	return "Missing required configurations: " + strings.Join(fields, "; ")
+}
+
+// CheckRequired checks if all fields with tag required:"true" of the given
+// structure are set, a field is regarded as unset if it has zero value.
+// Normally, CheckRequired should be called after all other parsing functions
+func CheckRequired(i interface{}) error {
+	ptrRef := reflect.ValueOf(i)
+	if ptrRef.Kind() != reflect.Ptr || ptrRef.IsNil() ||
+		ptrRef.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("Expect a structure pointer type instead of %s",
+			ptrRef.Kind().String())
+	}
+
+	return checkRequired(ptrRef.Elem(), "", nil)
+}
+
+// checkRequired checks required fields of the given structure value, the
+// envPrefix is used to report environment variable names. A field with zero
+// value is set if any source sets it in origins
+func checkRequired(v reflect.Value, envPrefix string,
+	origins map[string]Origin) error {
+	var missing []MissingField
+	err := walkFields(v, envPrefix, func(f *field) error {
+		required, ok := f.field.Tag.Lookup("required")
+		if !ok {
+			return nil
+		}
+
+		isRequired, err := strconv.ParseBool(required)
+		if err != nil {
+			return fmt.Errorf("%s: invalid required tag: %s", f.path, required)
+		}
+
+		if isRequired && f.value.IsZero() &&
+			lookupOrigin(origins, f.path).Source == "" {
+			missing = append(missing, MissingField{Path: f.path, Names: f.names})
+		}
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if len(missing) > 0 {
+		return &RequiredError{Fields: missing}
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+/*
+ * Copyright (C) 2017 eschao <esc.chao@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package config
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/eschao/config/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckRequired(t *testing.T) {
+	assert := assert.New(t)
+	conf := test.RequiredConfig{}
+	err := CheckRequired(&conf)
+	assert.Error(err)
+
+	var reqErr *RequiredError
+	assert.True(errors.As(err, &reqErr))
+	assert.Equal(4, len(reqErr.Fields))
+	assert.Equal("Host", reqErr.Fields[0].Path)
+	assert.Equal("Port", reqErr.Fields[1].Path)
+	assert.Equal("DB.Host", reqErr.Fields[2].Path)
+	assert.Equal("DB.Password", reqErr.Fields[3].Path)
+	assert.Equal(map[string]string{
+		"json": "db.host",
+		"yaml": "db.host",
+		"env":  "DB_HOST",
+		"cli":  "db -host",
+	}, reqErr.Fields[2].Names)
+	assert.Equal("DB.Host (json: db.host, yaml: db.host, env: DB_HOST, "+
+		"cli: db -host)", reqErr.Fields[2].String())
+
+	conf = test.RequiredConfig{
+		Host: "localhost",
+		Port: 8080,
+		DB:   test.RequiredDBConfig{Host: "db-host", Password: "passwd"},
+	}
+	assert.NoError(CheckRequired(&conf))
+}
+
+func TestLoaderWithRequired(t *testing.T) {
+	os.Setenv("CONFIG_TEST_REQUIRED_DB_HOST", "db-host")
+	defer os.Unsetenv("CONFIG_TEST_REQUIRED_DB_HOST")
+
+	assert := assert.New(t)
+	conf := test.RequiredConfig{}
+	loader := New(WithSources(DefaultSource, EnvSource, CliSource),
+		WithEnvPrefix("CONFIG_TEST_REQUIRED_"),
+		WithArgs("required", []string{"-host", "localhost"}))
+	err := loader.Load(&conf)
+	assert.Error(err)
+
+	var reqErr *RequiredError
+	assert.True(errors.As(err, &reqErr))
+	assert.Equal(1, len(reqErr.Fields))
+	assert.Equal("DB.Password", reqErr.Fields[0].Path)
+	assert.Equal("CONFIG_TEST_REQUIRED_DB_PASSWORD",
+		reqErr.Fields[0].Names["env"])
+
+	os.Setenv("CONFIG_TEST_REQUIRED_DB_PASSWORD", "passwd")
+	defer os.Unsetenv("CONFIG_TEST_REQUIRED_DB_PASSWORD")
+	assert.NoError(loader.Load(&conf))
+}
+
+func TestLoaderWithRequiredZeroValues(t *testing.T) {
+	os.Setenv("CONFIG_TEST_REQUIRED_DB_PASSWORD", "")
+	defer os.Unsetenv("CONFIG_TEST_REQUIRED_DB_PASSWORD")
+
+	assert := assert.New(t)
+	conf := test.RequiredConfig{}
+	loader := New(WithSources(EnvSource, CliSource),
+		WithEnvPrefix("CONFIG_TEST_REQUIRED_"),
+		WithArgs("required", []string{"-host", "", "-port", "0",
+			"db", "-host", "db-host"}))
+	assert.NoError(loader.Load(&conf))
+	assert.Equal(0, conf.Port)
+	assert.Equal("", conf.DB.Password)
+}
@@ -0,0 +1,247 @@
+/*
+ * Copyright (C) 2017 eschao <esc.chao@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// GenerateSample writes a sample configuration of given structure type, a
+// reflect.Type or a value of the structure or its pointer, in Yaml, JSON,
+// TOML, Properties or environment variable format, see EnvFormat. The values
+// are the default values in default tag or zero values, and the usages in cli
+// tag are the comments except JSON which has no comments. The nested
+// structures of pointers are included
+func GenerateSample(w io.Writer, i interface{}, format string) error {
+	typeOfStruct, err := structTypeOf(i)
+	if err != nil {
+		return err
+	}
+
+	v := reflect.New(typeOfStruct).Elem()
+	allocStructs(v, nil)
+	if err := parseValue(v); err != nil {
+		return err
+	}
+
+	comment := func(path string, f reflect.StructField) string {
+		return cliUsage(f)
+	}
+
+	switch format {
+	case YamlConfigType:
+		dumper := &treeDumper{tag: "yaml", comment: comment}
+		return writeYaml(w, dumper.nodes(v, "", false), true)
+	case JSONConfigType:
+		dumper := &treeDumper{tag: "json", comment: comment}
+		return writeJSON(w, dumper.nodes(v, "", false), false)
+	case TomlConfigType:
+		dumper := &treeDumper{tag: "toml", comment: comment}
+		return writeToml(w, dumper.nodes(v, "", false))
+	case PropConfigType, EnvFormat:
+		return writeLines(w, v, format, func(f *field) string {
+			if isStructField(f.field) {
+				return ""
+			}
+			return cliUsage(f.field)
+		}, false)
+	}
+
+	return fmt.Errorf("Can't support sample format: %s", format)
+}
+
+// allocStructs allocates the nil pointers to structure of structure value
+// recursively. The parents are the structure types containing the value, a
+// pointer to any of them is kept nil for recursive types
+func allocStructs(v reflect.Value, parents []reflect.Type) {
+	parents = append(parents, v.Type())
+	for i := 0; i < v.NumField(); i++ {
+		valueOfField := v.Field(i)
+		if !valueOfField.CanSet() {
+			continue
+		}
+
+		if valueOfField.Kind() == reflect.Ptr &&
+			valueOfField.Type().Elem().Kind() == reflect.Struct {
+			if valueOfField.IsNil() {
+				if containsType(parents, valueOfField.Type().Elem()) {
+					continue
+				}
+				valueOfField.Set(reflect.New(valueOfField.Type().Elem()))
+			}
+			valueOfField = valueOfField.Elem()
+		}
+
+		if valueOfField.Kind() == reflect.Struct {
+			allocStructs(valueOfField, parents)
+		}
+	}
+}
+
+// writeToml writes the nodes as TOML, the nested structures are tables and
+// the comments are written above keys
+func writeToml(w io.Writer, nodes []*dumpNode) error {
+	var buf bytes.Buffer
+	if err := writeTomlTable(&buf, nodes, ""); err != nil {
+		return err
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// writeTomlTable writes the key/value pairs of a table and then its sub
+// tables
+func writeTomlTable(buf *bytes.Buffer, nodes []*dumpNode, table string) error {
+	for _, node := range nodes {
+		if node.children != nil {
+			continue
+		}
+
+		// TOML has no null value
+		if isNilValue(node.value) {
+			continue
+		}
+
+		s, err := tomlValue(reflect.ValueOf(node.value))
+		if err != nil {
+			return fmt.Errorf("%s: %s", joinPath(table, node.name, "."),
+				err.Error())
+		}
+
+		if node.comment != "" {
+			fmt.Fprintf(buf, "# %s\n", node.comment)
+		}
+		fmt.Fprintf(buf, "%s = %s\n", tomlKey(node.name), s)
+	}
+
+	for _, node := range nodes {
+		if node.children == nil {
+			continue
+		}
+
+		if buf.Len() > 0 {
+			buf.WriteString("\n")
+		}
+		if node.comment != "" {
+			fmt.Fprintf(buf, "# %s\n", node.comment)
+		}
+
+		name := joinPath(table, tomlKey(node.name), ".")
+		fmt.Fprintf(buf, "[%s]\n", name)
+		if err := writeTomlTable(buf, node.children, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isNilValue checks if a value is nil or a nil pointer
+func isNilValue(value interface{}) bool {
+	v := reflect.ValueOf(value)
+	return !v.IsValid() || (v.Kind() == reflect.Ptr && v.IsNil())
+}
+
+// bareKey matches the TOML keys which needn't be quoted
+var bareKey = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// tomlKey quotes a TOML key if it is not a bare key
+func tomlKey(key string) string {
+	if bareKey.MatchString(key) {
+		return key
+	}
+	return strconv.Quote(key)
+}
+
+// tomlValue returns the TOML string of a value, the structures and maps are
+// inline tables
+func tomlValue(v reflect.Value) (string, error) {
+	if v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return "", fmt.Errorf("Can't support nil value")
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16,
+		reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8,
+		reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return fmt.Sprint(v.Interface()), nil
+	case reflect.Float32, reflect.Float64:
+		s := strconv.FormatFloat(v.Float(), 'f', -1, 64)
+		if !strings.ContainsAny(s, ".eEnN") {
+			s += ".0"
+		}
+		return s, nil
+	case reflect.String:
+		return strconv.Quote(v.String()), nil
+	case reflect.Slice, reflect.Array:
+		elements := make([]string, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			s, err := tomlValue(v.Index(i))
+			if err != nil {
+				return "", err
+			}
+			elements[i] = s
+		}
+		return "[" + strings.Join(elements, ", ") + "]", nil
+	case reflect.Map:
+		pairs := make([]string, 0, v.Len())
+		for _, key := range v.MapKeys() {
+			s, err := tomlValue(v.MapIndex(key))
+			if err != nil {
+				return "", err
+			}
+			pairs = append(pairs, tomlKey(fmt.Sprint(key.Interface()))+" = "+s)
+		}
+		sort.Strings(pairs)
+		return "{" + strings.Join(pairs, ", ") + "}", nil
+	case reflect.Struct:
+		dumper := &treeDumper{tag: "toml"}
+		return tomlInlineTable(dumper.nodes(v, "", false))
+	}
+
+	return "", fmt.Errorf("Can't support type: %s", v.Kind().String())
+}
+
+// tomlInlineTable returns the TOML inline table of nodes
+func tomlInlineTable(nodes []*dumpNode) (string, error) {
+	pairs := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		var s string
+		var err error
+		if node.children != nil {
+			s, err = tomlInlineTable(node.children)
+		} else if isNilValue(node.value) {
+			continue
+		} else {
+			s, err = tomlValue(reflect.ValueOf(node.value))
+		}
+		if err != nil {
+			return "", err
+		}
+		pairs = append(pairs, tomlKey(node.name)+" = "+s)
+	}
+	return "{" + strings.Join(pairs, ", ") + "}", nil
+}
@@ -0,0 +1,132 @@
+/*
+ * Copyright (C) 2017 eschao <esc.chao@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package config
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/eschao/config/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateSampleYaml(t *testing.T) {
+	assert := assert.New(t)
+	var buf bytes.Buffer
+	assert.NoError(GenerateSample(&buf, test.AppConfig{}, YamlConfigType))
+	assert.Equal("# application name\nname: test-app\n"+
+		"# application port\nport: 8080\n"+
+		"# debug mode\ndebug: true\n"+
+		"# application log configuration\nlog:\n"+
+		"  # log path\n  path: \"\"\n"+
+		"  # log level {debug|warning|error}\n  level: \"\"\n", buf.String())
+}
+
+func TestGenerateSampleTOML(t *testing.T) {
+	assert := assert.New(t)
+	var buf bytes.Buffer
+	assert.NoError(GenerateSample(&buf, test.DBConfig{}, TomlConfigType))
+	assert.Equal("# database server hostname\ndbHost = \"\"\n"+
+		"# database server port\ndbPort = 0\n"+
+		"# database username\ndbUser = \"\"\n"+
+		"# database user password\ndbPassword = \"\"\n\n"+
+		"# database log configuration\n[log]\n"+
+		"# log path\npath = \"\"\n"+
+		"# log level {debug|warning|error}\nlevel = \"\"\n", buf.String())
+
+	// the nested structures of pointers are included
+	buf.Reset()
+	assert.NoError(GenerateSample(&buf, &test.MergeConfig{}, TomlConfigType))
+	assert.Contains(buf.String(), "Labels = {}\n")
+	assert.Contains(buf.String(), "\n[DB.log]\n")
+	assert.Contains(buf.String(), "\n[Log]\n")
+
+	conf := test.MergeConfig{}
+	assert.NoError(ParseBytes(&conf, buf.Bytes(), TomlConfigType))
+	assert.NotNil(conf.Log)
+}
+
+func TestGenerateSampleLines(t *testing.T) {
+	assert := assert.New(t)
+	var buf bytes.Buffer
+	assert.NoError(GenerateSample(&buf, test.AppConfig{}, PropConfigType))
+	assert.Equal("# application name\nname = test-app\n"+
+		"# application port\nport = 8080\n"+
+		"# debug mode\ndebug = true\n"+
+		"# log path\nlog.path = \n"+
+		"# log level {debug|warning|error}\nlog.level = \n", buf.String())
+
+	buf.Reset()
+	assert.NoError(GenerateSample(&buf, reflect.TypeOf(test.ServiceConfig{}),
+		EnvFormat))
+	assert.Contains(buf.String(),
+		"# database server port\nCONFIG_TEST_SERVICE_DB_PORT=0\n")
+	assert.Contains(buf.String(),
+		"# login password\nCONFIG_TEST_SERVICE_LOGIN_PASSWORD=\n")
+}
+
+func TestGenerateSampleRoundTrip(t *testing.T) {
+	assert := assert.New(t)
+	expected := test.DefValueConfig{}
+	assert.NoError(ParseDefault(&expected))
+
+	for _, format := range []string{YamlConfigType, JSONConfigType,
+		TomlConfigType} {
+		var buf bytes.Buffer
+		assert.NoError(GenerateSample(&buf, test.AppConfig{}, format), format)
+
+		conf := test.AppConfig{}
+		assert.NoError(ParseBytes(&conf, buf.Bytes(), format), format)
+		assert.Equal(test.AppConfig{Name: "test-app", Port: 8080, Debug: true},
+			conf, format)
+
+		buf.Reset()
+		assert.NoError(GenerateSample(&buf, test.DefValueConfig{}, format),
+			format)
+		defConf := test.DefValueConfig{}
+		assert.NoError(ParseBytes(&defConf, buf.Bytes(), format), format)
+		assert.Equal(expected, defConf, format)
+	}
+
+	var buf bytes.Buffer
+	assert.Error(GenerateSample(&buf, test.AppConfig{}, "xml"))
+	assert.Error(GenerateSample(&buf, "config", YamlConfigType))
+}
+
+type sampleNode struct {
+	Name string      `json:"name" yaml:"name" toml:"name" prop:"name" env:"NAME" cli:"name node name" default:"node"`
+	Next *sampleNode `json:"next" yaml:"next" toml:"next" prop:"next" env:"NEXT_" cli:"next next node"`
+	Leaf *struct {
+		Parent *sampleNode `json:"parent" yaml:"parent" toml:"parent" prop:"parent" env:"PARENT_"`
+		Value  int         `json:"value" yaml:"value" toml:"value" prop:"value" env:"VALUE" default:"1"`
+	} `json:"leaf" yaml:"leaf" toml:"leaf" prop:"leaf" env:"LEAF_"`
+}
+
+func TestGenerateSampleWithRecursiveTypes(t *testing.T) {
+	assert := assert.New(t)
+	var buf bytes.Buffer
+	assert.NoError(GenerateSample(&buf, sampleNode{}, YamlConfigType))
+	assert.Equal("# node name\nname: node\n# next node\nnext: null\n"+
+		"leaf:\n  parent: null\n  value: 1\n", buf.String())
+
+	for _, format := range []string{JSONConfigType, TomlConfigType,
+		PropConfigType, EnvFormat} {
+		buf.Reset()
+		assert.NoError(GenerateSample(&buf, sampleNode{}, format), format)
+		assert.Contains(buf.String(), "node", format)
+	}
+}
@@ -0,0 +1,380 @@
+/*
+ * Copyright (C) 2017 eschao <esc.chao@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/eschao/config/utils"
+)
+
+// SchemaDraft is the JSON Schema dialect of generated schemas
+const SchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema is a JSON Schema which could be marshaled by encoding/json
+type Schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	Ref                  string             `json:"$ref,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties interface{}        `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	OneOf                []*Schema          `json:"oneOf,omitempty"`
+	Default              interface{}        `json:"default,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Minimum              json.Number        `json:"minimum,omitempty"`
+	Maximum              json.Number        `json:"maximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Defs                 map[string]*Schema `json:"$defs,omitempty"`
+}
+
+// schemaGenerator generates the schemas of structure types. The named
+// structure types used by more than one field, including the recursive
+// ones, are defined once in $defs of the root schema and referred by $ref
+type schemaGenerator struct {
+	tag   string
+	root  reflect.Type
+	uses  map[reflect.Type]int
+	names map[reflect.Type]string
+	defs  map[string]*Schema
+}
+
+// GenerateSchema generates a JSON Schema (draft 2020-12) of given structure
+// type, a reflect.Type or a value of the structure or its pointer. The
+// properties are named by the tag, json or yaml. The default, required and
+// validation tags are mapped to the corresponding keywords, and the usages in
+// cli tag are the descriptions. A required field with default value is not
+// required in configuration files. Unknown properties are not allowed except
+// the include key of the root object, see IncludeKey. The structure types
+// used by more than one field are defined in $defs
+func GenerateSchema(i interface{}, tag string) (*Schema, error) {
+	typeOfStruct, err := structTypeOf(i)
+	if err != nil {
+		return nil, err
+	}
+
+	if tag != "json" && tag != "yaml" {
+		return nil, fmt.Errorf("Can't support schema tag: %s", tag)
+	}
+
+	generator := &schemaGenerator{
+		tag:   tag,
+		root:  typeOfStruct,
+		uses:  make(map[reflect.Type]int),
+		names: make(map[reflect.Type]string),
+		defs:  make(map[string]*Schema),
+	}
+	generator.countUses(typeOfStruct)
+
+	schema := &Schema{Schema: SchemaDraft, Title: typeOfStruct.Name()}
+	if err := generator.structSchema(schema, typeOfStruct,
+		nil); err != nil {
+		return nil, err
+	}
+
+	if len(generator.defs) > 0 {
+		schema.Defs = generator.defs
+	}
+
+	if _, ok := schema.Properties[IncludeKey]; !ok {
+		schema.Properties[IncludeKey] = &Schema{
+			Description: "configuration files to include",
+			OneOf: []*Schema{{Type: "string"},
+				{Type: "array", Items: &Schema{Type: "string"}}},
+		}
+	}
+	return schema, nil
+}
+
+// structTypeOf returns the structure type of given reflect.Type, structure or
+// structure pointer
+func structTypeOf(i interface{}) (reflect.Type, error) {
+	t, ok := i.(reflect.Type)
+	if !ok {
+		t = reflect.TypeOf(i)
+	}
+
+	if t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("Expect a structure type instead of %v", t)
+	}
+	return t, nil
+}
+
+// countUses counts the fields using every structure type, the fields of a
+// type are only walked at its first use
+func (this *schemaGenerator) countUses(t reflect.Type) {
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+		this.countUses(t.Elem())
+		return
+	case reflect.Struct:
+	default:
+		return
+	}
+
+	this.uses[t]++
+	if this.uses[t] > 1 {
+		return
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		structOfField := t.Field(i)
+		if structOfField.PkgPath != "" {
+			continue
+		}
+		if _, ok := treeName(structOfField, this.tag); ok {
+			this.countUses(structOfField.Type)
+		}
+	}
+}
+
+// structSchema sets the properties of a structure type to the object schema,
+// the fields of embedded structures are promoted. The embedding is the
+// structure types which embed the given one
+func (this *schemaGenerator) structSchema(schema *Schema, t reflect.Type,
+	embedding []reflect.Type) error {
+	schema.Type = "object"
+	schema.AdditionalProperties = false
+	if schema.Properties == nil {
+		schema.Properties = make(map[string]*Schema)
+	}
+
+	embedding = append(embedding, t)
+	for i := 0; i < t.NumField(); i++ {
+		structOfField := t.Field(i)
+		if structOfField.PkgPath != "" {
+			continue
+		}
+
+		name, ok := treeName(structOfField, this.tag)
+		if !ok {
+			continue
+		}
+
+		if name == "" {
+			embedded := derefType(structOfField.Type)
+			if containsType(embedding, embedded) {
+				continue
+			}
+			if err := this.structSchema(schema, embedded,
+				embedding); err != nil {
+				return err
+			}
+			continue
+		}
+
+		property, err := this.fieldSchema(structOfField)
+		if err != nil {
+			return fmt.Errorf("%s: %s", structOfField.Name, err.Error())
+		}
+		schema.Properties[name] = property
+
+		required, _ := strconv.ParseBool(structOfField.Tag.Get("required"))
+		if _, ok := structOfField.Tag.Lookup("default"); required && !ok {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+	return nil
+}
+
+// containsType checks if the type is in the list
+func containsType(types []reflect.Type, t reflect.Type) bool {
+	for _, e := range types {
+		if e == t {
+			return true
+		}
+	}
+	return false
+}
+
+// fieldSchema returns the schema of a structure field
+func (this *schemaGenerator) fieldSchema(f reflect.StructField) (*Schema,
+	error) {
+	schema, err := this.typeSchema(f.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	schema.Description = cliUsage(f)
+
+	t := derefType(f.Type)
+	if defValue, ok := f.Tag.Lookup("default"); ok {
+		v := reflect.New(t).Elem()
+		sp, ok := f.Tag.Lookup("separator")
+		if !ok {
+			sp = ":"
+		}
+		if err := utils.SetValue(v, defValue, sp); err != nil {
+			return nil, fmt.Errorf("invalid default tag: %s", err.Error())
+		}
+		schema.Default = v.Interface()
+	}
+
+	for _, rule := range validateRules {
+		if param, ok := f.Tag.Lookup(rule); ok {
+			if err := ruleSchema(schema, t, rule, param); err != nil {
+				return nil, fmt.Errorf("invalid %s tag: %s", rule,
+					err.Error())
+			}
+		}
+	}
+	return schema, nil
+}
+
+// typeSchema returns the schema of a type
+func (this *schemaGenerator) typeSchema(t reflect.Type) (*Schema, error) {
+	t = derefType(t)
+	schema := &Schema{}
+	switch t.Kind() {
+	case reflect.Bool:
+		schema.Type = "boolean"
+	case reflect.String:
+		schema.Type = "string"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Int64:
+		schema.Type = "integer"
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64:
+		schema.Type = "integer"
+		schema.Minimum = "0"
+	case reflect.Float32, reflect.Float64:
+		schema.Type = "number"
+	case reflect.Slice, reflect.Array:
+		items, err := this.typeSchema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		schema.Type, schema.Items = "array", items
+	case reflect.Map:
+		values, err := this.typeSchema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		schema.Type, schema.AdditionalProperties = "object", values
+	case reflect.Struct:
+		if t == this.root {
+			schema.Ref = "#"
+		} else if t.Name() != "" && this.uses[t] > 1 {
+			name, err := this.define(t)
+			if err != nil {
+				return nil, err
+			}
+			schema.Ref = "#/$defs/" + name
+		} else if err := this.structSchema(schema, t, nil); err != nil {
+			return nil, err
+		}
+	case reflect.Interface:
+		// any value
+	default:
+		return nil, fmt.Errorf("Can't support type: %s", t.Kind().String())
+	}
+	return schema, nil
+}
+
+// define defines a structure type in $defs once and returns its name, the
+// types with same name in different packages are numbered
+func (this *schemaGenerator) define(t reflect.Type) (string, error) {
+	if name, ok := this.names[t]; ok {
+		return name, nil
+	}
+
+	name := t.Name()
+	for n := 2; this.defs[name] != nil; n++ {
+		name = fmt.Sprintf("%s%d", t.Name(), n)
+	}
+
+	// the type is registered before its fields for recursive types
+	schema := &Schema{}
+	this.names[t], this.defs[name] = name, schema
+	if err := this.structSchema(schema, t, nil); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// ruleSchema sets the keywords of a validation rule to the schema. The
+// length rules are applied to the string or slice, and other rules are
+// applied to every element of a slice, like validateValue does
+func ruleSchema(schema *Schema, t reflect.Type, rule, param string) error {
+	switch rule {
+	case "len", "minlen", "maxlen":
+		n, err := strconv.Atoi(param)
+		if err != nil {
+			return err
+		}
+
+		min, max := &schema.MinLength, &schema.MaxLength
+		if t.Kind() == reflect.Slice {
+			min, max = &schema.MinItems, &schema.MaxItems
+		}
+		if rule != "maxlen" {
+			*min = &n
+		}
+		if rule != "minlen" {
+			*max = &n
+		}
+		return nil
+	}
+
+	if t.Kind() == reflect.Slice {
+		return ruleSchema(schema.Items, derefType(t.Elem()), rule, param)
+	}
+
+	switch rule {
+	case "min", "max":
+		if _, err := strconv.ParseFloat(param, 64); err != nil {
+			return err
+		}
+		if rule == "min" {
+			schema.Minimum = json.Number(param)
+		} else {
+			schema.Maximum = json.Number(param)
+		}
+	case "oneof":
+		for _, option := range strings.Split(param, "|") {
+			v := reflect.New(t).Elem()
+			if err := utils.SetValue(v, option, ""); err != nil {
+				return err
+			}
+			schema.Enum = append(schema.Enum, v.Interface())
+		}
+	case "pattern":
+		schema.Pattern = param
+	}
+	return nil
+}
+
+// derefType returns the element type of a pointer type
+func derefType(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Ptr {
+		return t.Elem()
+	}
+	return t
+}
@@ -0,0 +1,153 @@
+/*
+ * Copyright (C) 2017 eschao <esc.chao@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/eschao/config/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateSchema(t *testing.T) {
+	assert := assert.New(t)
+	schema, err := GenerateSchema(&test.RequiredConfig{}, "json")
+	assert.NoError(err)
+	assert.Equal(SchemaDraft, schema.Schema)
+	assert.Equal("RequiredConfig", schema.Title)
+	assert.Equal("object", schema.Type)
+	assert.Equal(false, schema.AdditionalProperties)
+	assert.Equal([]string{"host"}, schema.Required)
+
+	port := schema.Properties["port"]
+	assert.Equal("integer", port.Type)
+	assert.Equal(8080, port.Default)
+	assert.Equal("service port", port.Description)
+
+	db := schema.Properties["db"]
+	assert.Equal("object", db.Type)
+	assert.Equal("database configuration", db.Description)
+	assert.Equal([]string{"host", "password"}, db.Required)
+	assert.Equal("string", db.Properties["password"].Type)
+
+	assert.NotNil(schema.Properties[IncludeKey])
+
+	data, err := json.Marshal(schema.Properties["user"])
+	assert.NoError(err)
+	assert.Equal(`{"description":"service user","type":"string"}`, string(data))
+}
+
+func TestGenerateSchemaWithRules(t *testing.T) {
+	assert := assert.New(t)
+	schema, err := GenerateSchema(reflect.TypeOf(test.ValidateConfig{}), "yaml")
+	assert.NoError(err)
+
+	// untagged fields are named in lower case like Yaml does
+	name := schema.Properties["name"]
+	assert.Equal("^[a-z][a-z0-9-]*$", name.Pattern)
+	assert.Equal(16, *name.MaxLength)
+	assert.Nil(name.MinLength)
+
+	port := schema.Properties["port"]
+	assert.Equal(json.Number("1"), port.Minimum)
+	assert.Equal(json.Number("65535"), port.Maximum)
+
+	retries := schema.Properties["retries"]
+	assert.Equal(json.Number("0"), retries.Minimum)
+	assert.Equal([]interface{}{uint8(1), uint8(3), uint8(5)}, retries.Enum)
+
+	hosts := schema.Properties["hosts"]
+	assert.Equal("array", hosts.Type)
+	assert.Equal(1, *hosts.MinItems)
+	assert.Equal(3, *hosts.MaxItems)
+	assert.Equal("^[a-z.]+$", hosts.Items.Pattern)
+
+	codes := schema.Properties["codes"]
+	assert.Equal(json.Number("100"), codes.Items.Minimum)
+	assert.Equal(json.Number("599"), codes.Items.Maximum)
+
+	level := schema.Properties["log"].Properties["level"]
+	assert.Equal([]interface{}{"debug", "warning", "error"}, level.Enum)
+	assert.Equal("log level {debug|warning|error}", level.Description)
+
+	data, err := json.Marshal(schema.Properties["ratio"])
+	assert.NoError(err)
+	assert.Equal(`{"description":"sample ratio","type":"number","minimum":0,`+
+		`"maximum":1}`, string(data))
+}
+
+func TestGenerateSchemaWithContainers(t *testing.T) {
+	assert := assert.New(t)
+	schema, err := GenerateSchema(test.MergeConfig{}, "json")
+	assert.NoError(err)
+
+	labels := schema.Properties["labels"]
+	assert.Equal("object", labels.Type)
+	assert.Equal(&Schema{Type: "string"}, labels.AdditionalProperties)
+
+	servers := schema.Properties["servers"]
+	assert.Equal("array", servers.Type)
+	assert.Equal("integer", servers.Items.Properties["port"].Type)
+
+	// LogConfig is used by log and db.log
+	log := schema.Properties["log"]
+	assert.Equal("#/$defs/LogConfig", log.Ref)
+	assert.Equal("#/$defs/LogConfig", schema.Properties["db"].Properties["log"].Ref)
+	assert.Equal("object", schema.Defs["LogConfig"].Type)
+	assert.Equal("string", schema.Defs["LogConfig"].Properties["path"].Type)
+
+	_, err = GenerateSchema(test.MergeConfig{}, "toml")
+	assert.Error(err)
+	_, err = GenerateSchema("config", "json")
+	assert.Error(err)
+}
+
+type schemaNode struct {
+	Name     string        `json:"name" oneof:"a|b" required:"true"`
+	Next     *schemaNode   `json:"next"`
+	Children []*schemaNode `json:"children"`
+}
+
+type schemaTree struct {
+	Root  schemaNode            `json:"root"`
+	Nodes map[string]schemaNode `json:"nodes"`
+	Tree  *schemaTree           `json:"tree"`
+}
+
+func TestGenerateSchemaWithRecursiveTypes(t *testing.T) {
+	assert := assert.New(t)
+	schema, err := GenerateSchema(schemaTree{}, "json")
+	assert.NoError(err)
+
+	// the root type is referred by #
+	assert.Equal("#", schema.Properties["tree"].Ref)
+	assert.Equal("#/$defs/schemaNode", schema.Properties["root"].Ref)
+	assert.Equal("#/$defs/schemaNode",
+		schema.Properties["nodes"].AdditionalProperties.(*Schema).Ref)
+
+	node := schema.Defs["schemaNode"]
+	assert.Equal(1, len(schema.Defs))
+	assert.Equal("#/$defs/schemaNode", node.Properties["next"].Ref)
+	assert.Equal("#/$defs/schemaNode", node.Properties["children"].Items.Ref)
+
+	assert.Equal([]interface{}{"a", "b"}, node.Properties["name"].Enum)
+	assert.Equal([]string{"name"}, node.Required)
+
+	_, err = json.Marshal(schema)
+	assert.NoError(err)
+}
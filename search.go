@@ -0,0 +1,109 @@
+/*
+ * Copyright (C) 2017 eschao <esc.chao@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultConfigNames are the default base names of configuration files
+var DefaultConfigNames = []string{"config"}
+
+// configExts are the extension names of configuration files in search order
+var configExts = []string{".json", ".yaml", ".yml", ".properties", ".toml",
+	".ini"}
+
+// NotFoundError is returned if no configuration file is found, it contains
+// all file paths which were tried
+type NotFoundError struct {
+	Paths []string
+}
+
+func (this *NotFoundError) Error() string {
+	return "No config file found in paths:\n  " +
+		strings.Join(this.Paths, "\n  ")
+}
+
+// DefaultSearchPaths returns the folders to search configuration files of
+// the given application in order:
+//   - current working directory
+//   - $XDG_CONFIG_HOME/<app>, or $HOME/.config/<app> if XDG_CONFIG_HOME is not
+//     set
+//   - $HOME/.<app>
+//   - /etc/<app>
+//   - the folder of executable
+//
+// The folders depending on application are skipped if app is empty
+func DefaultSearchPaths(app string) []string {
+	var paths []string
+	if wd, err := os.Getwd(); err == nil {
+		paths = append(paths, wd)
+	}
+
+	home, _ := os.UserHomeDir()
+	if app != "" {
+		if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+			paths = append(paths, filepath.Join(xdg, app))
+		} else if home != "" {
+			paths = append(paths, filepath.Join(home, ".config", app))
+		}
+
+		if home != "" {
+			paths = append(paths, filepath.Join(home, "."+app))
+		}
+		paths = append(paths, filepath.Join("/etc", app))
+	}
+
+	if exe, err := os.Executable(); err == nil {
+		paths = append(paths, filepath.Dir(exe))
+	}
+
+	return paths
+}
+
+// FindConfigFile searches configuration files in the given folders in order,
+// and returns the first existing one. In every folder, the base names are
+// checked in order with the extension names: .json, .yaml, .yml,
+// .properties, .toml and .ini. A NotFoundError is returned if no file exists
+func FindConfigFile(names []string, paths []string) (string, error) {
+	var tried []string
+	for _, path := range paths {
+		for _, name := range names {
+			for _, ext := range configExts {
+				file := filepath.Join(path, name+ext)
+				if info, err := os.Stat(file); err == nil && !info.IsDir() {
+					return file, nil
+				}
+				tried = append(tried, file)
+			}
+		}
+	}
+
+	return "", &NotFoundError{Paths: tried}
+}
+
+// appName returns the application name from executable file name
+func appName() string {
+	exe, err := os.Executable()
+	if err != nil {
+		return ""
+	}
+
+	name := filepath.Base(exe)
+	return strings.TrimSuffix(name, filepath.Ext(name))
+}
@@ -0,0 +1,94 @@
+/*
+ * Copyright (C) 2017 eschao <esc.chao@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/eschao/config/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultSearchPaths(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", "")
+
+	assert := assert.New(t)
+	wd, _ := os.Getwd()
+	paths := DefaultSearchPaths("app")
+	assert.Equal([]string{wd, filepath.Join(home, ".config", "app"),
+		filepath.Join(home, ".app"), "/etc/app"}, paths[:4])
+
+	t.Setenv("XDG_CONFIG_HOME", "/xdg")
+	paths = DefaultSearchPaths("app")
+	assert.Equal("/xdg/app", paths[1])
+
+	// application folders are skipped without name
+	paths = DefaultSearchPaths("")
+	assert.Equal(2, len(paths))
+	assert.Equal(wd, paths[0])
+}
+
+func TestFindConfigFile(t *testing.T) {
+	_, curTestFile, _, _ := runtime.Caller(0)
+	path := filepath.Join(filepath.Dir(curTestFile), "test")
+
+	assert := assert.New(t)
+	empty := t.TempDir()
+	file, err := FindConfigFile([]string{"service", "app"},
+		[]string{empty, path})
+	assert.NoError(err)
+	assert.Equal(filepath.Join(path, "service.ini"), file)
+
+	file, err = FindConfigFile([]string{"app"}, []string{path})
+	assert.NoError(err)
+	assert.Equal(filepath.Join(path, "app.yaml"), file)
+
+	_, err = FindConfigFile([]string{"none"}, []string{empty, path})
+	var notFound *NotFoundError
+	assert.True(errors.As(err, &notFound))
+	assert.Equal(2*len(configExts), len(notFound.Paths))
+	assert.True(strings.Contains(err.Error(),
+		filepath.Join(empty, "none.json")))
+	assert.True(strings.Contains(err.Error(),
+		filepath.Join(path, "none.ini")))
+}
+
+func TestLoaderWithSearchPaths(t *testing.T) {
+	_, curTestFile, _, _ := runtime.Caller(0)
+	path := filepath.Join(filepath.Dir(curTestFile), "test")
+
+	assert := assert.New(t)
+	conf := test.AppConfig{}
+	loader := New(WithSources(FileSource), WithConfigNames("app"),
+		WithSearchPaths(t.TempDir(), path))
+	assert.NoError(loader.Load(&conf))
+	assert.Equal("yaml-app", conf.Name)
+	assert.Equal(9090, conf.Port)
+
+	// no config file found is not an error of Loader
+	conf = test.AppConfig{}
+	loader = New(WithSources(FileSource), WithConfigNames("none"),
+		WithSearchPaths(path))
+	assert.NoError(loader.Load(&conf))
+	assert.Equal("", conf.Name)
+}
@@ -0,0 +1,91 @@
+/*
+ * Copyright (C) 2017 eschao <esc.chao@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// DefaultReloadDebounce is the default duration to wait for more signals
+// before reloading
+const DefaultReloadDebounce = 200 * time.Millisecond
+
+// OnReload registers a callback which is called with the outcome of every
+// reload of a Store: nil if succeeded, otherwise the error
+func OnReload(fn func(err error)) Option {
+	return func(loader *Loader) {
+		loader.onReload = append(loader.onReload, fn)
+	}
+}
+
+// WithReloadDebounce sets the duration to wait for more signals before
+// reloading, the default is DefaultReloadDebounce
+func WithReloadDebounce(debounce time.Duration) Option {
+	return func(loader *Loader) {
+		loader.debounce = debounce
+	}
+}
+
+// ReloadOnSignal reloads the store in background once the process receives
+// any of the given signals, until the context is done. The default signal
+// is SIGHUP. A burst of signals only triggers one reload after no more signal
+// is received within the debounce duration. The outcome of reloads is
+// reported by OnReload, OnChange and OnError callbacks of the store Loader.
+// The Store must be created by LoadStore
+func ReloadOnSignal[T any](ctx context.Context, store *Store[T],
+	signals ...os.Signal) error {
+	if store.loader == nil {
+		return fmt.Errorf("Can't reload a store without loader")
+	}
+
+	if len(signals) == 0 {
+		signals = []os.Signal{syscall.SIGHUP}
+	}
+
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, signals...)
+	debounce := store.loader.debounce
+	go func() {
+		defer signal.Stop(ch)
+
+		timer := time.NewTimer(debounce)
+		timer.Stop()
+		defer timer.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ch:
+				if !timer.Stop() {
+					select {
+					case <-timer.C:
+					default:
+					}
+				}
+				timer.Reset(debounce)
+			case <-timer.C:
+				store.Reload()
+			}
+		}
+	}()
+
+	return nil
+}
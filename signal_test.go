@@ -0,0 +1,87 @@
+//go:build !windows
+
+/*
+ * Copyright (C) 2017 eschao <esc.chao@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"context"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/eschao/config/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReloadOnSignal(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "app.yaml")
+	writeFiles(t, dir, map[string]string{"app.yaml": "name: v1\n"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	assert := assert.New(t)
+	outcomes := make(chan error, 4)
+	store, err := LoadStore[test.AppConfig](WithSources(FileSource),
+		WithConfigFile(file), WithReloadDebounce(50*time.Millisecond),
+		OnReload(func(err error) {
+			outcomes <- err
+		}))
+	assert.NoError(err)
+	assert.NoError(ReloadOnSignal(ctx, store))
+
+	// a burst of signals triggers one reload
+	writeFiles(t, dir, map[string]string{"app.yaml": "name: v2\n"})
+	for i := 0; i < 3; i++ {
+		assert.NoError(syscall.Kill(syscall.Getpid(), syscall.SIGHUP))
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	select {
+	case err := <-outcomes:
+		assert.NoError(err)
+		assert.Equal("v2", store.Load().Name)
+	case <-time.After(2 * time.Second):
+		t.Fatal("store is not reloaded")
+	}
+
+	select {
+	case <-outcomes:
+		t.Fatal("store is reloaded more than once")
+	case <-time.After(150 * time.Millisecond):
+	}
+
+	// invalid reload is reported
+	writeFiles(t, dir, map[string]string{"app.yaml": "log:\n  level: x\n"})
+	assert.NoError(syscall.Kill(syscall.Getpid(), syscall.SIGHUP))
+	select {
+	case err := <-outcomes:
+		assert.Error(err)
+		assert.Equal("v2", store.Load().Name)
+	case <-time.After(2 * time.Second):
+		t.Fatal("store is not reloaded")
+	}
+}
+
+func TestReloadOnSignalWithoutLoader(t *testing.T) {
+	assert := assert.New(t)
+	assert.Error(ReloadOnSignal(context.Background(),
+		NewStore(&test.AppConfig{})))
+}
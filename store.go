@@ -0,0 +1,156 @@
+/*
+ * Copyright (C) 2017 eschao <esc.chao@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package config
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+// Store holds a snapshot of configuration structure which could be read and
+// replaced by many goroutines without data races. A snapshot is immutable
+// after it is stored, so a new value must be swapped in instead of modifying
+// the loaded one
+type Store[T any] struct {
+	value       atomic.Pointer[T]
+	loader      *Loader
+	reloadMutex sync.Mutex
+	mutex       sync.Mutex
+	subscribers map[chan *T]struct{}
+}
+
+// NewStore creates a Store with the given configuration value
+func NewStore[T any](value *T) *Store[T] {
+	store := &Store[T]{subscribers: make(map[chan *T]struct{})}
+	store.value.Store(value)
+	return store
+}
+
+// LoadStore creates a Loader with given options and a Store with the value
+// loaded by it. The Store could be reloaded by the Loader later, see Reload
+// and Watch
+func LoadStore[T any](opts ...Option) (*Store[T], error) {
+	loader := New(opts...)
+	value := new(T)
+	if err := loader.Load(value); err != nil {
+		return nil, err
+	}
+
+	store := NewStore(value)
+	store.loader = loader
+	return store, nil
+}
+
+// Load returns the current configuration snapshot
+func (this *Store[T]) Load() *T {
+	return this.value.Load()
+}
+
+// Swap stores a new configuration snapshot and returns the old one. The new
+// one is sent to all subscribers
+func (this *Store[T]) Swap(value *T) *T {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	old := this.value.Swap(value)
+	for ch := range this.subscribers {
+		// only the latest snapshot is kept for a slow subscriber
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- value
+	}
+	return old
+}
+
+// Subscribe returns a channel receiving new snapshots once they are swapped
+// in and a function to cancel the subscription. The channel only keeps the
+// latest snapshot if the subscriber doesn't receive it in time
+func (this *Store[T]) Subscribe() (<-chan *T, func()) {
+	ch := make(chan *T, 1)
+	this.mutex.Lock()
+	this.subscribers[ch] = struct{}{}
+	this.mutex.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			this.mutex.Lock()
+			delete(this.subscribers, ch)
+			this.mutex.Unlock()
+		})
+	}
+}
+
+// Reload loads all sources with the Loader of store into a fresh value. If it
+// is valid and changed, it is swapped in and OnChange callbacks of the Loader
+// are called, otherwise the error is returned and reported by OnError
+// callbacks. OnReload callbacks are called with the outcome. The Store must
+// be created by LoadStore
+func (this *Store[T]) Reload() error {
+	if this.loader == nil {
+		return fmt.Errorf("Can't reload a store without loader")
+	}
+
+	this.reloadMutex.Lock()
+	defer this.reloadMutex.Unlock()
+
+	err := this.reload()
+	for _, fn := range this.loader.onReload {
+		fn(err)
+	}
+	return err
+}
+
+// reload loads a fresh value and swaps it in if it is valid and changed
+func (this *Store[T]) reload() error {
+	old := this.Load()
+	fresh := new(T)
+	if old != nil {
+		fresh = newValueLike(reflect.ValueOf(old).Elem()).Addr().Interface().(*T)
+	}
+
+	if err := this.loader.Load(fresh); err != nil {
+		this.loader.notifyError(err)
+		return err
+	}
+
+	if reflect.DeepEqual(old, fresh) {
+		return nil
+	}
+
+	this.Swap(fresh)
+	this.loader.notifyChange(old, fresh)
+	return nil
+}
+
+// Watch watches the configuration files used by the Loader of store in
+// background until the context is done, and reloads the store once they are
+// changed, see Loader.Watch. The Store must be created by LoadStore
+func (this *Store[T]) Watch(ctx context.Context) error {
+	if this.loader == nil {
+		return fmt.Errorf("Can't watch a store without loader")
+	}
+
+	this.loader.watch(ctx, reflect.TypeOf((*T)(nil)).Elem(), func() {
+		this.Reload()
+	})
+	return nil
+}
@@ -0,0 +1,132 @@
+/*
+ * Copyright (C) 2017 eschao <esc.chao@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package config
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/eschao/config/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStore(t *testing.T) {
+	assert := assert.New(t)
+	store := NewStore(&test.AppConfig{Name: "v1"})
+	assert.Equal("v1", store.Load().Name)
+
+	ch, cancel := store.Subscribe()
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				_ = store.Load().Name
+			}
+		}()
+	}
+
+	old := store.Swap(&test.AppConfig{Name: "v2"})
+	store.Swap(&test.AppConfig{Name: "v3"})
+	wg.Wait()
+	assert.Equal("v1", old.Name)
+	assert.Equal("v3", store.Load().Name)
+
+	// only the latest snapshot is kept
+	assert.Equal("v3", (<-ch).Name)
+	select {
+	case value := <-ch:
+		t.Fatalf("unexpected snapshot: %v", value)
+	default:
+	}
+
+	cancel()
+	cancel()
+	store.Swap(&test.AppConfig{Name: "v4"})
+	select {
+	case value := <-ch:
+		t.Fatalf("unexpected snapshot: %v", value)
+	default:
+	}
+}
+
+func TestLoadStore(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "app.yaml")
+	writeFiles(t, dir, map[string]string{"app.yaml": "name: v1\n"})
+
+	assert := assert.New(t)
+	var errs []error
+	store, err := LoadStore[test.AppConfig](WithSources(DefaultSource,
+		FileSource), WithConfigFile(file), OnError(func(err error) {
+		errs = append(errs, err)
+	}))
+	assert.NoError(err)
+	assert.Equal("v1", store.Load().Name)
+	assert.Equal(8080, store.Load().Port)
+
+	ch, cancel := store.Subscribe()
+	defer cancel()
+
+	writeFiles(t, dir, map[string]string{"app.yaml": "name: v2\n"})
+	assert.NoError(store.Reload())
+	assert.Equal("v2", (<-ch).Name)
+
+	// invalid reload is rejected
+	first := store.Load()
+	writeFiles(t, dir, map[string]string{"app.yaml": "log:\n  level: x\n"})
+	assert.Error(store.Reload())
+	assert.Equal(1, len(errs))
+	assert.True(first == store.Load())
+
+	_, err = LoadStore[test.AppConfig](WithSources(FileSource),
+		WithConfigFile(filepath.Join(dir, "not-exist.yaml")))
+	assert.Error(err)
+
+	assert.Error(NewStore(&test.AppConfig{}).Reload())
+	assert.Error(NewStore(&test.AppConfig{}).Watch(context.Background()))
+}
+
+func TestWatchStore(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "app.yaml")
+	writeFiles(t, dir, map[string]string{"app.yaml": "name: v1\n"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	assert := assert.New(t)
+	store, err := LoadStore[test.AppConfig](WithSources(FileSource),
+		WithConfigFile(file), WithWatchInterval(10*time.Millisecond))
+	assert.NoError(err)
+	assert.NoError(store.Watch(ctx))
+
+	ch, unsubscribe := store.Subscribe()
+	defer unsubscribe()
+
+	writeFiles(t, dir, map[string]string{"app.yaml": "name: v2\n"})
+	select {
+	case value := <-ch:
+		assert.Equal("v2", value.Name)
+		assert.Equal("v2", store.Load().Name)
+	case <-time.After(2 * time.Second):
+		t.Fatal("no snapshot is published")
+	}
+}
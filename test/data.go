@@ -16,29 +16,29 @@
 package test
 
 type DBConfig struct {
-	Host     string    `json:"dbHost"     yaml:"dbHost"     env:"HOST"     cli:"dbHost database server hostname"`
-	Port     int       `json:"dbPort"     yaml:"dbPort"     env:"PORT"     cli:"dbPort database server port"`
-	User     string    `json:"dbUser"     yaml:"dbUser"     env:"USER"     cli:"dbUser database username"`
-	Password string    `json:"dbPassword" yaml:"dbPassword" env:"PASSWORD" cli:"dbPassword database user password"`
-	Log      LogConfig `json:"log"        yaml:"log"        env:"LOG_"     cli:"log database log configuration"`
+	Host     string    `json:"dbHost"     yaml:"dbHost"     toml:"dbHost"     env:"HOST"     prop:"dbHost"     ini:"host"     cli:"dbHost database server hostname"`
+	Port     int       `json:"dbPort"     yaml:"dbPort"     toml:"dbPort"     env:"PORT"     prop:"dbPort"     ini:"port"     cli:"dbPort database server port"`
+	User     string    `json:"dbUser"     yaml:"dbUser"     toml:"dbUser"     env:"USER"     prop:"dbUser"     ini:"user"     cli:"dbUser database username"`
+	Password string    `json:"dbPassword" yaml:"dbPassword" toml:"dbPassword" env:"PASSWORD" prop:"dbPassword" ini:"password" cli:"dbPassword database user password" secret:"true"`
+	Log      LogConfig `json:"log"        yaml:"log"        toml:"log"        env:"LOG_"     prop:"log"        ini:"log"      cli:"log database log configuration"`
 }
 
 type LoginConfig struct {
-	User     string `json:"user"     yaml:"user"     env:"USER"     prop:"user"     cli:"user login username"`
-	Password string `json:"password" yaml:"password" env:"PASSWORD" prop:"password" cli:"password login password"`
+	User     string `json:"user"     yaml:"user"     env:"USER"     prop:"user"     ini:"user"     cli:"user login username"`
+	Password string `json:"password" yaml:"password" env:"PASSWORD" prop:"password" ini:"password" cli:"password login password" secret:"true"`
 }
 
 type LogConfig struct {
-	Path  string `json:"path"  yaml:"path"  env:"PATH"  prop:"path"  cli:"path log path"`
-	Level string `json:"level" yaml:"level" env:"LEVEL" porp:"level" cli:"level log level {debug|warning|error}"`
+	Path  string `json:"path"  yaml:"path"  toml:"path"  env:"PATH"  prop:"path"  ini:"path"  dir:"path"  cli:"path log path"`
+	Level string `json:"level" yaml:"level" toml:"level" env:"LEVEL" prop:"level" ini:"level" dir:"level" cli:"level log level {debug|warning|error}" oneof:"debug|warning|error"`
 }
 
 type ServiceConfig struct {
-	Host     string       `env:"CONFIG_TEST_SERVICE_HOST"   cli:"hostname service hostname"`
-	Port     int          `env:"CONFIG_TEST_SERVICE_PORT"   cli:"port service port"`
-	DBConfig DBConfig     `env:"CONFIG_TEST_SERVICE_DB_"    cli:"database database configuration"`
-	Login    *LoginConfig `env:"CONFIG_TEST_SERVICE_LOGIN_" cli:"login login user and password"`
-	Log      LogConfig    `env:"CONFIG_TEST_SERVICE_LOG_"   cli:"log service log configuration"`
+	Host     string       `ini:"hostname" env:"CONFIG_TEST_SERVICE_HOST"   cli:"hostname service hostname"`
+	Port     int          `ini:"port"     env:"CONFIG_TEST_SERVICE_PORT"   cli:"port service port"`
+	DBConfig DBConfig     `ini:"database" env:"CONFIG_TEST_SERVICE_DB_"    cli:"database database configuration"`
+	Login    *LoginConfig `ini:"login"    env:"CONFIG_TEST_SERVICE_LOGIN_" cli:"login login user and password"`
+	Log      LogConfig    `ini:"log"      env:"CONFIG_TEST_SERVICE_LOG_"   cli:"log service log configuration"`
 }
 
 type TypesConfig struct {
@@ -72,3 +72,65 @@ type SlicesConfig struct {
 	Debugs []string `env:"CONFIG_TEST_SLICES_DEBUG"  cli:"debugs multiple debug" separator:";"`
 	Values []int    `env:"CONFIG_TEST_SLICES_VALUES" cli:"values multiple value" separator:","`
 }
+
+type AppConfig struct {
+	Name  string    `json:"name"  yaml:"name"  prop:"name"  env:"CONFIG_TEST_APP_NAME"  cli:"name application name" default:"test-app"`
+	Port  int       `json:"port"  yaml:"port"  prop:"port"  env:"CONFIG_TEST_APP_PORT"  cli:"port application port" default:"8080"`
+	Debug bool      `json:"debug" yaml:"debug" prop:"debug" env:"CONFIG_TEST_APP_DEBUG" cli:"debug debug mode" default:"true"`
+	Log   LogConfig `json:"log"   yaml:"log"   prop:"log"   env:"CONFIG_TEST_APP_LOG_"  cli:"log application log configuration"`
+}
+
+type RequiredConfig struct {
+	Host string           `json:"host" yaml:"host" env:"HOST" cli:"host service hostname" required:"true"`
+	Port int              `json:"port" yaml:"port" env:"PORT" cli:"port service port" default:"8080" required:"true"`
+	User string           `json:"user" yaml:"user" env:"USER" cli:"user service user"`
+	DB   RequiredDBConfig `json:"db"   yaml:"db"   env:"DB_"  cli:"db database configuration"`
+}
+
+type RequiredDBConfig struct {
+	Host     string `json:"host"     yaml:"host"     env:"HOST"     cli:"host database hostname" required:"true"`
+	Password string `json:"password" yaml:"password" env:"PASSWORD" cli:"password database password" required:"true"`
+}
+
+type ValidateConfig struct {
+	Name    string    `env:"NAME"    cli:"name service name" pattern:"^[a-z][a-z0-9-]*$" maxlen:"16"`
+	Port    int       `env:"PORT"    cli:"port service port" min:"1" max:"65535"`
+	Ratio   float32   `env:"RATIO"   cli:"ratio sample ratio" min:"0" max:"1"`
+	Retries uint8     `env:"RETRIES" cli:"retries retry times" oneof:"1|3|5"`
+	Hosts   []string  `env:"HOSTS"   cli:"hosts service hosts" minlen:"1" maxlen:"3" pattern:"^[a-z.]+$"`
+	Codes   []int     `env:"CODES"   cli:"codes status codes" separator:"," min:"100" max:"599"`
+	Log     LogConfig `env:"LOG_"    cli:"log service log configuration"`
+}
+
+type MergeConfig struct {
+	Name    string            `json:"name"    yaml:"name"`
+	Plugins []string          `json:"plugins" yaml:"plugins" merge:"append"`
+	Hosts   []string          `json:"hosts"   yaml:"hosts"`
+	Labels  map[string]string `json:"labels"  yaml:"labels"`
+	Servers []ServerConfig    `json:"servers" yaml:"servers"`
+	DB      DBConfig          `json:"db"      yaml:"db"`
+	Log     *LogConfig        `json:"log"     yaml:"log"`
+}
+
+type ServerConfig struct {
+	Host string `json:"host" yaml:"host"`
+	Port int    `json:"port" yaml:"port"`
+}
+
+type InterpolateConfig struct {
+	Home    string            `json:"home"    env:"CONFIG_TEST_INTERPOLATE_HOME" default:"${CONFIG_TEST_HOME:-/home/test}"`
+	LogDir  string            `json:"logDir"  env:"CONFIG_TEST_INTERPOLATE_LOG_DIR" default:"${home}/logs"`
+	Paths   []string          `json:"paths"   separator:","`
+	Labels  map[string]string `json:"labels"`
+	Port    int               `json:"port"    default:"8080"`
+	Address string            `json:"address" default:"localhost:${port}"`
+	Token   string            `json:"token"   env:"CONFIG_TEST_INTERPOLATE_TOKEN" secret:"true"`
+	Log     LogConfig         `json:"log"`
+}
+
+type DirConfig struct {
+	Name  string    `dir:"name"  env:"NAME"`
+	Port  int       `dir:"port"  env:"PORT"`
+	Hosts []string  `dir:"hosts" env:"HOSTS" separator:","`
+	Log   LogConfig `dir:"log"   env:"LOG_"`
+}
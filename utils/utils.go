@@ -62,6 +62,44 @@ func SetValueWithUintX(v reflect.Value, uintValue string, bitSize int) error {
 	return nil
 }
 
+// SetValue converts the given string according to the kind of v and sets v
+// with the result. If v is a slice, the string is split by separator first
+func SetValue(v reflect.Value, value string, separator string) error {
+	kind := v.Kind()
+	switch kind {
+	case reflect.Bool:
+		return SetValueWithBool(v, value)
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Int8:
+		return SetValueWithIntX(v, value, 8)
+	case reflect.Int16:
+		return SetValueWithIntX(v, value, 16)
+	case reflect.Int, reflect.Int32:
+		return SetValueWithIntX(v, value, 32)
+	case reflect.Int64:
+		return SetValueWithIntX(v, value, 64)
+	case reflect.Uint8:
+		return SetValueWithUintX(v, value, 8)
+	case reflect.Uint16:
+		return SetValueWithUintX(v, value, 16)
+	case reflect.Uint, reflect.Uint32:
+		return SetValueWithUintX(v, value, 32)
+	case reflect.Uint64:
+		return SetValueWithUintX(v, value, 64)
+	case reflect.Float32:
+		return SetValueWithFloatX(v, value, 32)
+	case reflect.Float64:
+		return SetValueWithFloatX(v, value, 64)
+	case reflect.Slice:
+		return SetValueWithSlice(v, value, separator)
+	default:
+		return fmt.Errorf("Can't support type: %s", kind.String())
+	}
+
+	return nil
+}
+
 func SetValueWithSlice(v reflect.Value, slice string, separator string) error {
 	data := strings.Split(slice, separator)
 	size := len(data)
@@ -97,3 +97,21 @@ func TestSetValueWithSlice(t *testing.T) {
 	assert.Equal("yy", d.Names[1])
 	assert.Equal("zz", d.Names[2])
 }
+
+func TestSetValue(t *testing.T) {
+	d := data{}
+	ref := reflect.ValueOf(&d).Elem()
+
+	assert := assert.New(t)
+	assert.NoError(SetValue(ref.FieldByName("BoolValue"), "true", ""))
+	assert.NoError(SetValue(ref.FieldByName("Int8Value"), "-8", ""))
+	assert.NoError(SetValue(ref.FieldByName("UintValue"), "300", ""))
+	assert.NoError(SetValue(ref.FieldByName("Float32Value"), "1.5", ""))
+	assert.NoError(SetValue(ref.FieldByName("Names"), "xx,yy", ","))
+	assert.Equal(true, d.BoolValue)
+	assert.Equal(int8(-8), d.Int8Value)
+	assert.Equal(uint(300), d.UintValue)
+	assert.Equal(float32(1.5), d.Float32Value)
+	assert.Equal([]string{"xx", "yy"}, d.Names)
+	assert.Error(SetValue(ref.FieldByName("IntValue"), "xxx", ""))
+}
@@ -0,0 +1,268 @@
+/*
+ * Copyright (C) 2017 eschao <esc.chao@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/eschao/config/utils"
+)
+
+// validateRules are the supported validation tags in checking order
+var validateRules = []string{"len", "minlen", "maxlen", "min", "max", "oneof",
+	"pattern"}
+
+// FieldError describes a field which fails the validation
+type FieldError struct {
+	Path   string      // Go path of field, e.g: Log.Level
+	Source Source      // source which sets the field, empty if unknown
+	Rule   string      // validation rule, e.g: oneof
+	Param  string      // parameter of the rule, e.g: debug|warning|error
+	Value  interface{} // value of the field
+}
+
+func (this FieldError) String() string {
+	s := fmt.Sprintf("%s: %v %s", this.Path, this.Value, this.message())
+	if this.Source != "" {
+		s += fmt.Sprintf(" (set by %s)", this.Source)
+	}
+	return s
+}
+
+// message describes the failed rule
+func (this FieldError) message() string {
+	msg := ""
+	switch this.Rule {
+	case "len":
+		msg = "length must be " + this.Param
+	case "minlen":
+		msg = "length must be at least " + this.Param
+	case "maxlen":
+		msg = "length must be at most " + this.Param
+	case "min":
+		msg = "must be at least " + this.Param
+	case "max":
+		msg = "must be at most " + this.Param
+	case "oneof":
+		msg = "must be one of {" + this.Param + "}"
+	case "pattern":
+		msg = "must match pattern " + this.Param
+	}
+	return msg
+}
+
+// ValidationError reports all fields which fail the validation
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (this *ValidationError) Error() string {
+	fields := make([]string, len(this.Fields))
+	for i, f := range this.Fields {
+		fields[i] = f.String()
+	}
+	return "Invalid configurations: " + strings.Join(fields, "; ")
+}
+
+// Validate validates the fields of given structure with their validation
+// tags:
+//   - len, minlen, maxlen: length of string or slice
+//   - min, max: range of number or every number in slice
+//   - oneof: allowed values separated by '|', e.g: oneof:"debug|error"
+//   - pattern: regular expression which string must match
+//
+// Zero values are validated as well, use required tag to make sure the
+// fields are set. After that, the Validate() of structures which implement
+// Validator are called from the nested ones to the given one
+func Validate(i interface{}) error {
+	ptrRef := reflect.ValueOf(i)
+	if ptrRef.Kind() != reflect.Ptr || ptrRef.IsNil() ||
+		ptrRef.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("Expect a structure pointer type instead of %s",
+			ptrRef.Kind().String())
+	}
+
+	var errs Errors
+	if err := validateFields(ptrRef.Elem(), nil); err != nil {
+		errs = append(errs, err)
+	}
+
+	errs = append(errs, callValidate(ptrRef.Elem())...)
+	if len(errs) == 1 {
+		return errs[0]
+	} else if len(errs) > 1 {
+		return errs
+	}
+	return nil
+}
+
+// validateFields validates the given structure value, the origins tell
+// which source sets the field. If origins is not nil, the fields with zero
+// value which are not set by any source are left to required tag
+func validateFields(v reflect.Value, origins map[string]Origin) error {
+	var invalid []FieldError
+	err := walkFields(v, "", func(f *field) error {
+		if origins != nil && f.value.IsZero() &&
+			lookupOrigin(origins, f.path).Source == "" {
+			return nil
+		}
+
+		for _, rule := range validateRules {
+			param, ok := f.field.Tag.Lookup(rule)
+			if !ok {
+				continue
+			}
+
+			valid, err := validateValue(f.value, rule, param)
+			if err != nil {
+				return fmt.Errorf("%s: invalid %s tag: %s", f.path, rule,
+					err.Error())
+			}
+
+			if !valid {
+				invalid = append(invalid, FieldError{
+					Path:   f.path,
+					Source: lookupOrigin(origins, f.path).Source,
+					Rule:   rule,
+					Param:  param,
+					Value:  f.value.Interface(),
+				})
+			}
+		}
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if len(invalid) > 0 {
+		return &ValidationError{Fields: invalid}
+	}
+	return nil
+}
+
+// validateValue validates a value with the given rule. The length rules are
+// applied to the whole slice and other rules are applied to every element
+func validateValue(v reflect.Value, rule, param string) (bool, error) {
+	switch rule {
+	case "len", "minlen", "maxlen":
+		if v.Kind() != reflect.String && v.Kind() != reflect.Slice {
+			return false, fmt.Errorf("Can't support type: %s",
+				v.Kind().String())
+		}
+
+		n, err := strconv.Atoi(param)
+		if err != nil {
+			return false, err
+		}
+
+		length := v.Len()
+		if v.Kind() == reflect.String {
+			length = len([]rune(v.String()))
+		}
+
+		if rule == "len" {
+			return length == n, nil
+		} else if rule == "minlen" {
+			return length >= n, nil
+		}
+		return length <= n, nil
+	}
+
+	if v.Kind() == reflect.Slice {
+		for i := 0; i < v.Len(); i++ {
+			valid, err := validateValue(v.Index(i), rule, param)
+			if err != nil || !valid {
+				return valid, err
+			}
+		}
+		return true, nil
+	}
+
+	switch rule {
+	case "min", "max":
+		cmp, err := compareValue(v, param)
+		if err != nil {
+			return false, err
+		}
+		if rule == "min" {
+			return cmp >= 0, nil
+		}
+		return cmp <= 0, nil
+	case "oneof":
+		for _, option := range strings.Split(param, "|") {
+			expected := reflect.New(v.Type()).Elem()
+			if err := utils.SetValue(expected, option, ""); err != nil {
+				return false, err
+			}
+			if v.Interface() == expected.Interface() {
+				return true, nil
+			}
+		}
+		return false, nil
+	case "pattern":
+		if v.Kind() != reflect.String {
+			return false, fmt.Errorf("Can't support type: %s",
+				v.Kind().String())
+		}
+		return regexp.MatchString(param, v.String())
+	}
+
+	return false, fmt.Errorf("Can't support rule: %s", rule)
+}
+
+// compareValue compares a number value with the given number string, returns
+// -1, 0 or 1
+func compareValue(v reflect.Value, number string) (int, error) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Int64:
+		n, err := strconv.ParseInt(number, 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		return compare(v.Int() < n, v.Int() > n), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64:
+		n, err := strconv.ParseUint(number, 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		return compare(v.Uint() < n, v.Uint() > n), nil
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(number, v.Type().Bits())
+		if err != nil {
+			return 0, err
+		}
+		return compare(v.Float() < n, v.Float() > n), nil
+	}
+
+	return 0, fmt.Errorf("Can't support type: %s", v.Kind().String())
+}
+
+func compare(less, greater bool) int {
+	if less {
+		return -1
+	} else if greater {
+		return 1
+	}
+	return 0
+}
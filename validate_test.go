@@ -0,0 +1,113 @@
+/*
+ * Copyright (C) 2017 eschao <esc.chao@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package config
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/eschao/config/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	assert := assert.New(t)
+	conf := test.ValidateConfig{
+		Name:    "service-1",
+		Port:    8080,
+		Ratio:   0.5,
+		Retries: 3,
+		Hosts:   []string{"a.com", "b.com"},
+		Codes:   []int{200, 404},
+		Log:     test.LogConfig{Level: "debug"},
+	}
+	assert.NoError(Validate(&conf))
+
+	// zero values are validated
+	err := Validate(&test.ValidateConfig{})
+	assert.Error(err)
+
+	var valErr *ValidationError
+	assert.True(errors.As(err, &valErr))
+	assert.Equal(5, len(valErr.Fields))
+	assert.Equal("Name", valErr.Fields[0].Path)
+	assert.Equal(FieldError{Path: "Port", Rule: "min", Param: "1", Value: 0},
+		valErr.Fields[1])
+	assert.Equal("Retries", valErr.Fields[2].Path)
+	assert.Equal("Hosts", valErr.Fields[3].Path)
+	assert.Equal("Log.Level", valErr.Fields[4].Path)
+
+	conf = test.ValidateConfig{
+		Name:    "Service-1",
+		Port:    70000,
+		Ratio:   1.5,
+		Retries: 2,
+		Hosts:   []string{"a.com", "b.com", "c.com", "d.com"},
+		Codes:   []int{200, 600},
+		Log:     test.LogConfig{Level: "info"},
+	}
+	err = Validate(&conf)
+	assert.Error(err)
+
+	assert.True(errors.As(err, &valErr))
+	assert.Equal(7, len(valErr.Fields))
+	assert.Equal(FieldError{Path: "Name", Rule: "pattern",
+		Param: "^[a-z][a-z0-9-]*$", Value: "Service-1"}, valErr.Fields[0])
+	assert.Equal("max", valErr.Fields[1].Rule)
+	assert.Equal("Ratio", valErr.Fields[2].Path)
+	assert.Equal("Retries", valErr.Fields[3].Path)
+	assert.Equal("maxlen", valErr.Fields[4].Rule)
+	assert.Equal("Codes", valErr.Fields[5].Path)
+	assert.Equal("Log.Level", valErr.Fields[6].Path)
+	assert.Equal("Log.Level: info must be one of {debug|warning|error}",
+		valErr.Fields[6].String())
+
+	type invalidTag struct {
+		Name string `min:"1"`
+	}
+	err = Validate(&invalidTag{Name: "xxx"})
+	assert.Error(err)
+	assert.False(errors.As(err, &valErr))
+}
+
+func TestLoaderWithValidation(t *testing.T) {
+	os.Setenv("CONFIG_TEST_VALIDATE_PORT", "80")
+	os.Setenv("CONFIG_TEST_VALIDATE_LOG_LEVEL", "info")
+	defer os.Unsetenv("CONFIG_TEST_VALIDATE_PORT")
+	defer os.Unsetenv("CONFIG_TEST_VALIDATE_LOG_LEVEL")
+
+	assert := assert.New(t)
+	conf := test.ValidateConfig{}
+	loader := New(WithSources(EnvSource, CliSource),
+		WithEnvPrefix("CONFIG_TEST_VALIDATE_"),
+		WithArgs("validate", []string{"-port", "0", "-ratio", "2"}))
+	err := loader.Load(&conf)
+	assert.Error(err)
+
+	var valErr *ValidationError
+	assert.True(errors.As(err, &valErr))
+	assert.Equal(3, len(valErr.Fields))
+	assert.Equal("Port", valErr.Fields[0].Path)
+	assert.Equal(CliSource, valErr.Fields[0].Source)
+	assert.Equal(0, valErr.Fields[0].Value)
+	assert.Equal("Ratio", valErr.Fields[1].Path)
+	assert.Equal(CliSource, valErr.Fields[1].Source)
+	assert.Equal("Log.Level", valErr.Fields[2].Path)
+	assert.Equal(EnvSource, valErr.Fields[2].Source)
+	assert.Equal("Log.Level: info must be one of {debug|warning|error} "+
+		"(set by env)", valErr.Fields[2].String())
+}
@@ -0,0 +1,238 @@
+/*
+ * Copyright (C) 2017 eschao <esc.chao@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package config
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// DefaultWatchInterval is the default interval to check configuration files
+const DefaultWatchInterval = time.Second
+
+// OnChange registers a callback which is called with the old and new
+// configuration structure pointers after a reload is published
+func OnChange(fn func(old, new interface{})) Option {
+	return func(loader *Loader) {
+		loader.onChange = append(loader.onChange, fn)
+	}
+}
+
+// OnError registers a callback which is called with the error of a rejected
+// reload
+func OnError(fn func(err error)) Option {
+	return func(loader *Loader) {
+		loader.onError = append(loader.onError, fn)
+	}
+}
+
+// WithWatchInterval sets the interval to check configuration files for
+// changes, the default is DefaultWatchInterval
+func WithWatchInterval(interval time.Duration) Option {
+	return func(loader *Loader) {
+		loader.interval = interval
+	}
+}
+
+// Watch creates a Loader with given options and watches its configuration
+// files, see Loader.Watch
+func Watch(ctx context.Context, i interface{}, opts ...Option) error {
+	return New(opts...).Watch(ctx, i)
+}
+
+// Watch loads all sources into given structure pointer, and then watches the
+// configuration files used by the loader until the context is done, including
+// the included files, profile files and the files in configuration directory.
+// A file is changed if its content, modified time or symbolic link target is
+// changed, e.g: Kubernetes swaps ..data link to update a mounted ConfigMap.
+//
+// Once the files are changed, all sources are reloaded into a fresh value.
+// If the reload fails or is invalid, it is reported by OnError callbacks.
+// Otherwise, the fresh value is published to OnChange callbacks if it is
+// changed, and it must not be modified after that.
+//
+// It returns the error of the first loading, and the watching is running in
+// background. The given structure is only set by the first loading, reloaded
+// values are published by OnChange callbacks only, so callers decide how to
+// share them, e.g: swap them in a Store
+func (this *Loader) Watch(ctx context.Context, i interface{}) error {
+	if err := this.Load(i); err != nil {
+		return err
+	}
+
+	// the published value is compared with fresh ones in background, so it is
+	// a copy of the given structure which could be changed by callers
+	ptrRef := reflect.ValueOf(i)
+	current := reflect.New(ptrRef.Elem().Type())
+	current.Elem().Set(ptrRef.Elem())
+	this.watch(ctx, ptrRef.Elem().Type(), func() {
+		if fresh, ok := this.reload(current); ok {
+			current = fresh
+		}
+	})
+	return nil
+}
+
+// watch calls reload in background once the watched files of structure type
+// are changed, until the context is done
+func (this *Loader) watch(ctx context.Context, t reflect.Type,
+	reload func()) {
+	state := fingerprint(this.watchedFiles(t))
+	go func() {
+		ticker := time.NewTicker(this.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			current := fingerprint(this.watchedFiles(t))
+			if current == state {
+				continue
+			}
+			state = current
+			reload()
+		}
+	}()
+}
+
+// reload loads all sources into a fresh value, and publishes it by OnChange
+// callbacks if it is valid and different from the current one. It returns
+// the fresh value and true if it is published
+func (this *Loader) reload(current reflect.Value) (reflect.Value, bool) {
+	fresh := newValueLike(current.Elem()).Addr()
+	if err := this.Load(fresh.Interface()); err != nil {
+		this.notifyError(err)
+		return current, false
+	}
+
+	if reflect.DeepEqual(current.Elem().Interface(),
+		fresh.Elem().Interface()) {
+		return current, false
+	}
+
+	this.notifyChange(current.Interface(), fresh.Interface())
+	return fresh, true
+}
+
+// notifyChange calls OnChange callbacks
+func (this *Loader) notifyChange(old, new interface{}) {
+	for _, fn := range this.onChange {
+		fn(old, new)
+	}
+}
+
+// notifyError calls OnError callbacks
+func (this *Loader) notifyError(err error) {
+	for _, fn := range this.onError {
+		fn(err)
+	}
+}
+
+// watchedFiles returns the files which are used by the loader to load
+// structure type
+func (this *Loader) watchedFiles(t reflect.Type) []string {
+	configFiles, _, _ := this.resolveConfigFiles(t)
+	var files []string
+	visited := make(map[string]bool)
+	for _, configFile := range configFiles {
+		files = append(files, includedFiles(configFile, visited, 0)...)
+	}
+
+	if this.dir != "" && this.hasSource(DirSource) {
+		files = append(files, dirFiles(this.dir)...)
+	}
+	return files
+}
+
+// includedFiles returns the given config file and the files it includes
+// recursively
+func includedFiles(configFile string, visited map[string]bool,
+	depth int) []string {
+	files := []string{configFile}
+	abs, err := filepath.Abs(configFile)
+	if err != nil || visited[abs] || depth >= MaxIncludeDepth {
+		return files
+	}
+	visited[abs] = true
+
+	configType, err := getConfigFileType(configFile)
+	if err != nil {
+		return files
+	}
+
+	raw, err := ioutil.ReadFile(configFile)
+	if err != nil {
+		return files
+	}
+
+	includes, _ := getIncludes(raw, configType)
+	for _, include := range includes {
+		if !filepath.IsAbs(include) {
+			include = filepath.Join(filepath.Dir(abs), include)
+		}
+		files = append(files, includedFiles(include, visited, depth+1)...)
+	}
+	return files
+}
+
+// dirFiles returns the ..data link and all files in the configuration
+// directory
+func dirFiles(dir string) []string {
+	files := []string{filepath.Join(dir, dirDataLink)}
+	root := dir
+	if data, err := filepath.EvalSymlinks(filepath.Join(dir,
+		dirDataLink)); err == nil {
+		root = data
+	}
+
+	filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files
+}
+
+// fingerprint returns the state of files: their symbolic link targets and
+// SHA-256 hashes of contents
+func fingerprint(files []string) string {
+	var b strings.Builder
+	for _, file := range files {
+		b.WriteString(file)
+		if target, err := filepath.EvalSymlinks(file); err == nil {
+			b.WriteString(" -> " + target)
+		}
+
+		if raw, err := ioutil.ReadFile(file); err == nil {
+			fmt.Fprintf(&b, " %x", sha256.Sum256(raw))
+		} else {
+			b.WriteString(" missing")
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
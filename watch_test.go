@@ -0,0 +1,157 @@
+/*
+ * Copyright (C) 2017 eschao <esc.chao@gmail.com>
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/eschao/config/test"
+	"github.com/stretchr/testify/assert"
+)
+
+// change is the arguments of an OnChange callback
+type change struct {
+	old *test.AppConfig
+	new *test.AppConfig
+}
+
+func watchOptions(changes chan change, errs chan error,
+	opts ...Option) []Option {
+	return append(opts, WithSources(DefaultSource, FileSource),
+		WithWatchInterval(10*time.Millisecond),
+		OnChange(func(old, new interface{}) {
+			changes <- change{old.(*test.AppConfig), new.(*test.AppConfig)}
+		}),
+		OnError(func(err error) {
+			errs <- err
+		}))
+}
+
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "app.yaml")
+	writeFiles(t, dir, map[string]string{
+		"app.yaml": "name: v1\nlog:\n  level: debug\n",
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	assert := assert.New(t)
+	changes, errs := make(chan change, 1), make(chan error, 1)
+	conf := test.AppConfig{}
+	assert.NoError(Watch(ctx, &conf, watchOptions(changes, errs,
+		WithConfigFile(file))...))
+
+	writeFiles(t, dir, map[string]string{
+		"app.yaml": "name: v2\nlog:\n  level: debug\n",
+	})
+	select {
+	case c := <-changes:
+		assert.Equal("v1", c.old.Name)
+		assert.Equal("v2", c.new.Name)
+		assert.Equal(8080, c.new.Port)
+	case <-time.After(2 * time.Second):
+		t.Fatal("no change is published")
+	}
+	// the given structure is not written in background
+	assert.Equal("v1", conf.Name)
+
+	// invalid reload is rejected
+	writeFiles(t, dir, map[string]string{
+		"app.yaml": "name: v3\nlog:\n  level: verbose\n",
+	})
+	select {
+	case err := <-errs:
+		assert.Error(err)
+	case <-changes:
+		t.Fatal("invalid change is published")
+	case <-time.After(2 * time.Second):
+		t.Fatal("no error is reported")
+	}
+
+	select {
+	case c := <-changes:
+		t.Fatalf("unexpected change: %v", c.new)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestWatchWithDataLink(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"..v1/app.yaml": "name: v1\n",
+		"..v2/app.yaml": "name: v2\n",
+	})
+
+	assert := assert.New(t)
+	assert.NoError(os.Symlink("..v1", filepath.Join(dir, "..data")))
+	assert.NoError(os.Symlink("..data/app.yaml",
+		filepath.Join(dir, "app.yaml")))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changes, errs := make(chan change, 1), make(chan error, 1)
+	conf := test.AppConfig{}
+	assert.NoError(Watch(ctx, &conf, watchOptions(changes, errs,
+		WithConfigFile(filepath.Join(dir, "app.yaml")))...))
+
+	assert.NoError(os.Symlink("..v2", filepath.Join(dir, "..data_tmp")))
+	assert.NoError(os.Rename(filepath.Join(dir, "..data_tmp"),
+		filepath.Join(dir, "..data")))
+	select {
+	case c := <-changes:
+		assert.Equal("v1", c.old.Name)
+		assert.Equal("v2", c.new.Name)
+	case err := <-errs:
+		t.Fatal(err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("no change is published")
+	}
+}
+
+func TestWatchIncludedFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"app.yaml": "include: log.yaml\nname: app\n",
+		"log.yaml": "log:\n  path: /var/log/v1\n",
+	})
+
+	assert := assert.New(t)
+	loader := New(WithConfigFile(filepath.Join(dir, "app.yaml")))
+	files := loader.watchedFiles(reflect.TypeOf(test.AppConfig{}))
+	assert.Equal([]string{filepath.Join(dir, "app.yaml"),
+		filepath.Join(dir, "log.yaml")}, files)
+
+	state := fingerprint(files)
+	writeFiles(t, dir, map[string]string{
+		"log.yaml": "log:\n  path: /var/log/v2\n",
+	})
+	assert.NotEqual(state, fingerprint(loader.watchedFiles(reflect.TypeOf(test.AppConfig{}))))
+}
+
+func TestWatchWithError(t *testing.T) {
+	assert := assert.New(t)
+	conf := test.AppConfig{}
+	assert.Error(Watch(context.Background(), &conf,
+		WithSources(FileSource), WithConfigFile("not-exist.yaml")))
+}